@@ -0,0 +1,148 @@
+// File: src/cmd/budget.go
+package main
+
+import (
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Rough number of bytes per token for typical source code and prose
+const bytesPerToken = 4
+
+// estimateTokens approximates the token count for a file of the given size
+func estimateTokens(size int64) int {
+	return int((size + bytesPerToken - 1) / bytesPerToken)
+}
+
+// warnLargeFiles logs a warning for every file estimated at more than threshold
+// tokens, leaving the selection unchanged. Files written as placeholders are skipped.
+func warnLargeFiles(logger *slog.Logger, entries []fileEntry, threshold int) {
+	for _, entry := range entries {
+		if entry.Note != "" {
+			continue
+		}
+		if tokens := entry.tokens(); tokens > threshold {
+			logger.Warn("File exceeds token warning threshold", "file", entry.RelativePath, "tokens", tokens, "threshold", threshold)
+		}
+	}
+}
+
+// truncateToBudget keeps files in walk order until the token budget is exhausted
+func truncateToBudget(logger *slog.Logger, entries []fileEntry, maxTokens int) []fileEntry {
+	used := 0
+	for i, entry := range entries {
+		tokens := entry.tokens()
+		if used+tokens > maxTokens {
+			for _, dropped := range entries[i:] {
+				logger.Info("Dropped file over token budget", "file", dropped.RelativePath, "tokens", dropped.tokens())
+			}
+			return entries[:i]
+		}
+		used += tokens
+	}
+	return entries
+}
+
+// fitBudget selects the subset of files with the highest total relevance that fits
+// within maxTokens. It is a greedy knapsack: files are taken in order of relevance
+// per token, and the selection is returned in the original walk order.
+func fitBudget(logger *slog.Logger, entries []fileEntry, maxTokens int) []fileEntry {
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+
+	density := func(i int) float64 {
+		// Empty files cost nothing, so count them as a single token to keep the ratio finite
+		tokens := entries[i].tokens()
+		if tokens == 0 {
+			tokens = 1
+		}
+		return relevanceScore(entries[i].RelativePath) / float64(tokens)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return density(order[a]) > density(order[b])
+	})
+
+	selected := make([]bool, len(entries))
+	used := 0
+	for _, i := range order {
+		tokens := entries[i].tokens()
+		if used+tokens <= maxTokens {
+			selected[i] = true
+			used += tokens
+		}
+	}
+
+	var kept []fileEntry
+	for i, entry := range entries {
+		if selected[i] {
+			kept = append(kept, entry)
+			continue
+		}
+		logger.Info("Dropped file to fit token budget", "file", entry.RelativePath,
+			"tokens", entry.tokens(), "score", relevanceScore(entry.RelativePath))
+	}
+	logger.Info("Selected files within token budget", "files", len(kept), "dropped", len(entries)-len(kept), "tokens", used, "maxTokens", maxTokens)
+	return kept
+}
+
+// Files that describe the project as a whole and are worth the most context
+var projectFiles = map[string]bool{
+	"readme.md":        true,
+	"readme":           true,
+	"go.mod":           true,
+	"package.json":     true,
+	"cargo.toml":       true,
+	"pyproject.toml":   true,
+	"makefile":         true,
+	"dockerfile":       true,
+	"requirements.txt": true,
+}
+
+// Extensions treated as source code when scoring relevance
+var sourceExtensions = map[string]bool{
+	".go": true, ".py": true, ".js": true, ".ts": true, ".tsx": true, ".jsx": true,
+	".rs": true, ".java": true, ".c": true, ".h": true, ".cpp": true, ".hpp": true,
+	".cs": true, ".rb": true, ".php": true, ".swift": true, ".kt": true, ".sh": true,
+}
+
+// Extensions treated as documentation when scoring relevance
+var docExtensions = map[string]bool{
+	".md": true, ".rst": true, ".txt": true, ".adoc": true,
+}
+
+// relevanceScore is a heuristic value for a file: project manifests and source code
+// score higher than docs, tests and vendored or generated code score lower, and
+// deeply nested files are discounted slightly.
+func relevanceScore(relativePath string) float64 {
+	slashPath := filepath.ToSlash(relativePath)
+	base := strings.ToLower(filepath.Base(slashPath))
+	ext := strings.ToLower(filepath.Ext(base))
+
+	score := 1.0
+	switch {
+	case projectFiles[base]:
+		score = 3.0
+	case sourceExtensions[ext]:
+		score = 2.0
+	case docExtensions[ext]:
+		score = 1.5
+	}
+
+	if strings.HasSuffix(base, "_test.go") || strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") || strings.HasPrefix(base, "test_") {
+		score *= 0.75
+	}
+
+	for _, part := range strings.Split(slashPath, "/") {
+		switch part {
+		case "vendor", "node_modules", "testdata", "third_party", "generated":
+			score *= 0.25
+		}
+	}
+
+	depth := strings.Count(slashPath, "/")
+	return score / (1 + 0.1*float64(depth))
+}
@@ -0,0 +1,111 @@
+// File: src/cmd/budget_test.go
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestEstimateTokens checks that token estimates round up
+func TestEstimateTokens(t *testing.T) {
+	cases := []struct {
+		size     int64
+		expected int
+	}{
+		{0, 0},
+		{1, 1},
+		{4, 1},
+		{5, 2},
+		{400, 100},
+	}
+
+	for _, c := range cases {
+		if got := estimateTokens(c.size); got != c.expected {
+			t.Errorf("estimateTokens(%d) = %d, expected %d", c.size, got, c.expected)
+		}
+	}
+}
+
+// TestTruncateToBudget checks that files after the limit are dropped in walk order
+func TestTruncateToBudget(t *testing.T) {
+	logger := getLogger()
+	entries := []fileEntry{
+		{RelativePath: "a.go", Size: 40},
+		{RelativePath: "b.go", Size: 400},
+		{RelativePath: "c.go", Size: 4},
+	}
+
+	kept := truncateToBudget(logger, entries, 20)
+	if len(kept) != 1 || kept[0].RelativePath != "a.go" {
+		t.Errorf("Expected only a.go to be kept, got %v", kept)
+	}
+}
+
+// TestWarnLargeFiles checks that only files over the threshold are reported, and not
+// files replaced by a placeholder
+func TestWarnLargeFiles(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	entries := []fileEntry{
+		{RelativePath: "small.go", Size: 40},
+		{RelativePath: "large.go", Size: 400},
+		{RelativePath: "edge.go", Size: 80},
+		{RelativePath: "linked.go", Size: 400, Note: "# HARDLINK TO: " + strings.Repeat("very/long/", 10) + "large.go"},
+	}
+
+	warnLargeFiles(logger, entries, 20)
+
+	if !strings.Contains(logs.String(), "file=large.go tokens=100 threshold=20") {
+		t.Errorf("Expected a warning for large.go, got logs:\n%s", logs.String())
+	}
+	for _, quiet := range []string{"small.go", "edge.go", "linked.go"} {
+		if strings.Contains(logs.String(), "file="+quiet) {
+			t.Errorf("Expected no warning for %s, got logs:\n%s", quiet, logs.String())
+		}
+	}
+}
+
+// TestFitBudget checks that the knapsack selection prefers relevant files and keeps walk order
+func TestFitBudget(t *testing.T) {
+	logger := getLogger()
+	entries := []fileEntry{
+		{RelativePath: "vendor/lib/big.go", Size: 400},
+		{RelativePath: "README.md", Size: 200},
+		{RelativePath: "main.go", Size: 200},
+		{RelativePath: "notes.bin", Size: 200},
+	}
+
+	kept := fitBudget(logger, entries, 100)
+
+	var got []string
+	for _, entry := range kept {
+		got = append(got, entry.RelativePath)
+	}
+	expected := []string{"README.md", "main.go"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+	}
+}
+
+// TestRelevanceScore checks the relative ordering of the relevance heuristic
+func TestRelevanceScore(t *testing.T) {
+	if relevanceScore("go.mod") <= relevanceScore("main.go") {
+		t.Errorf("Expected project manifest to outrank source code")
+	}
+	if relevanceScore("main.go") <= relevanceScore("main_test.go") {
+		t.Errorf("Expected source code to outrank tests")
+	}
+	if relevanceScore("pkg/main.go") <= relevanceScore("vendor/pkg/main.go") {
+		t.Errorf("Expected first-party code to outrank vendored code")
+	}
+	if relevanceScore("main.go") <= relevanceScore("a/b/c/main.go") {
+		t.Errorf("Expected shallow files to outrank deeply nested ones")
+	}
+}
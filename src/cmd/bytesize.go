@@ -0,0 +1,77 @@
+// File: src/cmd/bytesize.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Default I/O buffer size, matching bufio's own default
+const defaultBufferSize = 4096
+
+// byteSize is a size in bytes that can be written human-readably, e.g. 64KB or 1MB.
+// Units are binary, so 1KB is 1024 bytes.
+type byteSize int64
+
+// Multipliers for the units parseByteSize accepts, longest suffixes first
+var byteSizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// parseByteSize parses a positive size such as 4096, 512B, 64KB or 1MiB
+func parseByteSize(value string) (byteSize, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if number, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, multiplier = strings.TrimSpace(number), unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q: expected a positive number with an optional KB, MB or GB suffix", value)
+	}
+	if n > (1<<62)/multiplier {
+		return 0, fmt.Errorf("invalid size %q: too large", value)
+	}
+	return byteSize(n * multiplier), nil
+}
+
+// ioBufferSize returns the configured buffer size, or the default when it is unset
+func ioBufferSize(cfg *Config) int {
+	if cfg.BufferSize <= 0 {
+		return defaultBufferSize
+	}
+	return int(cfg.BufferSize)
+}
+
+func (b *byteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSize) Set(value string) error {
+	size, err := parseByteSize(value)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// UnmarshalJSON accepts either a plain number of bytes or a human-readable string
+func (b *byteSize) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		text = string(data)
+	}
+	return b.Set(text)
+}
@@ -0,0 +1,36 @@
+// File: src/cmd/bytesize_test.go
+package main
+
+import "testing"
+
+// TestParseByteSize checks plain and suffixed sizes and rejects nonsense
+func TestParseByteSize(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected byteSize
+	}{
+		{"4096", 4096},
+		{"512B", 512},
+		{"64KB", 64 << 10},
+		{"64k", 64 << 10},
+		{"1MB", 1 << 20},
+		{"1 MiB", 1 << 20},
+		{"2GB", 2 << 30},
+	}
+	for _, c := range cases {
+		got, err := parseByteSize(c.value)
+		if err != nil {
+			t.Errorf("parseByteSize(%q) failed: %v", c.value, err)
+			continue
+		}
+		if got != c.expected {
+			t.Errorf("parseByteSize(%q) = %d, expected %d", c.value, got, c.expected)
+		}
+	}
+
+	for _, value := range []string{"", "0", "-1KB", "1.5MB", "MB", "12XB"} {
+		if _, err := parseByteSize(value); err == nil {
+			t.Errorf("Expected parseByteSize(%q) to fail", value)
+		}
+	}
+}
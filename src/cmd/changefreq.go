@@ -0,0 +1,69 @@
+// File: src/cmd/changefreq.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Values accepted by -sort; an empty value keeps the walk order
+var sortOrders = []string{"change-frequency-desc"}
+
+// Format of the header field added by -emit-change-frequency. It follows the
+// -git-diff-stat fields.
+const changeCountFieldFormat = " change-count=%d"
+
+// gitChangeCounts counts the commits that touched each file in repoPath, keyed by path
+// relative to repoPath. A file's count matches git log --oneline -- <file> | wc -l,
+// but all files are counted with a single git log.
+func gitChangeCounts(ctx context.Context, repoPath string) (map[string]int, error) {
+	output, err := runGitCommand(ctx, repoPath, nil, "log", "--format=", "--name-only", "-z", "--no-renames", "--relative", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, name := range strings.Split(string(output), "\x00") {
+		// Each commit's list of names starts on a new line
+		if name = strings.TrimLeft(name, "\n"); name != "" {
+			counts[filepath.FromSlash(name)]++
+		}
+	}
+	return counts, nil
+}
+
+// addChangeCounts sets the change count of every entry; files git has no history
+// for get zero
+func addChangeCounts(entries []fileEntry, counts map[string]int) {
+	for i := range entries {
+		count := counts[entries[i].RelativePath]
+		entries[i].ChangeCount = &count
+	}
+}
+
+// sortByChangeFrequency puts the most frequently changed files first, keeping the
+// walk order among files with the same count
+func sortByChangeFrequency(entries []fileEntry, counts map[string]int) {
+	slices.SortStableFunc(entries, func(a, b fileEntry) int {
+		return counts[b.RelativePath] - counts[a.RelativePath]
+	})
+}
+
+// validateSortOrder checks the value of -sort
+func validateSortOrder(order string) error {
+	if order == "" || slices.Contains(sortOrders, order) {
+		return nil
+	}
+	return fmt.Errorf("unknown sort order %q (supported: %s)", order, strings.Join(sortOrders, ", "))
+}
+
+// changeCountField renders the -emit-change-frequency header field for entry, if it
+// has a change count
+func changeCountField(entry fileEntry) string {
+	if entry.ChangeCount == nil {
+		return ""
+	}
+	return fmt.Sprintf(changeCountFieldFormat, *entry.ChangeCount)
+}
@@ -0,0 +1,86 @@
+// File: src/cmd/changefreq_test.go
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestEmitChangeFrequency checks that headers carry the number of commits that touched
+// each file, matching git log, and that -sort puts the busiest files first
+func TestEmitChangeFrequency(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repoDir := createFixtureRepo(t, map[string]string{
+		"a_rare.txt":      "v0\n",
+		"b_busy.txt":      "v0\n",
+		"c_moderate.txt":  "v0\n",
+		"d_untracked.txt": "new\n",
+	})
+	runGit(t, repoDir, "init", "-q")
+	runGit(t, repoDir, "add", "a_rare.txt", "b_busy.txt", "c_moderate.txt")
+	runGit(t, repoDir, "commit", "-q", "-m", "initial")
+	for i, files := range [][]string{
+		{"b_busy.txt", "c_moderate.txt"},
+		{"b_busy.txt"},
+		{"b_busy.txt", "c_moderate.txt"},
+	} {
+		for _, name := range files {
+			if err := os.WriteFile(filepath.Join(repoDir, name), []byte("v"+strconv.Itoa(i+1)+"\n"), 0644); err != nil {
+				t.Fatalf("Failed to modify fixture file: %v", err)
+			}
+		}
+		runGit(t, repoDir, append([]string{"commit", "-q", "-m", "change " + strconv.Itoa(i+1), "--"}, files...)...)
+	}
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, EmitChangeFrequency: true})
+	for _, name := range []string{"a_rare.txt", "b_busy.txt", "c_moderate.txt"} {
+		cmd := exec.Command("git", "log", "--oneline", "--", name)
+		cmd.Dir = repoDir
+		log, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git log failed: %v", err)
+		}
+		header := "# BEGIN FILE: " + name + " change-count=" + strconv.Itoa(strings.Count(string(log), "\n")) + "\n"
+		if !strings.Contains(output, header) {
+			t.Errorf("Expected %q, got:\n%s", header, output)
+		}
+	}
+	for _, header := range []string{
+		"# BEGIN FILE: b_busy.txt change-count=4\n",
+		"# BEGIN FILE: c_moderate.txt change-count=3\n",
+		"# BEGIN FILE: d_untracked.txt change-count=0\n",
+	} {
+		if !strings.Contains(output, header) {
+			t.Errorf("Expected %q, got:\n%s", header, output)
+		}
+	}
+
+	extracted, err := parseCombinedOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if len(extracted) != 4 || extracted[0].path != "a_rare.txt" {
+		t.Errorf("Expected extract to strip the change-count field, got %+v", extracted)
+	}
+
+	output = runAndReadOutput(t, &Config{RepoPath: repoDir, Sort: "change-frequency-desc"})
+	if strings.Contains(output, "change-count=") {
+		t.Errorf("Expected no change-count fields without -emit-change-frequency")
+	}
+	var order []string
+	for _, line := range strings.Split(output, "\n") {
+		if name, ok := strings.CutPrefix(line, "# BEGIN FILE: "); ok {
+			order = append(order, name)
+		}
+	}
+	expected := []string{"b_busy.txt", "c_moderate.txt", "a_rare.txt", "d_untracked.txt"}
+	if strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected files in order %v, got %v", expected, order)
+	}
+}
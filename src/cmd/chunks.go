@@ -0,0 +1,140 @@
+// File: src/cmd/chunks.go
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// Header field marking one chunk of a file split by -chunk-tokens. It comes before
+// the escaped field.
+const chunkFieldFormat = " chunk=%d/%d"
+
+// chunkContent splits content into chunks of at most maxBytes, breaking only at line
+// ends. With respectSymbols, Go and Python files are broken at top-level
+// declarations where possible, and only declarations larger than a chunk are split
+// between lines. A single line longer than maxBytes becomes a chunk of its own.
+func chunkContent(content []byte, relativePath string, maxBytes int, respectSymbols bool) [][]byte {
+	if maxBytes <= 0 || len(content) <= maxBytes {
+		return [][]byte{content}
+	}
+	var boundaries []int
+	if respectSymbols {
+		boundaries = symbolBoundaries(content, relativePath)
+	}
+	if boundaries == nil {
+		return chunkLines(content, maxBytes)
+	}
+
+	var chunks [][]byte
+	start, last := 0, 0
+	for _, boundary := range append(boundaries, len(content)) {
+		if boundary-start > maxBytes && last > start {
+			chunks = append(chunks, content[start:last])
+			start = last
+		}
+		if boundary-start > maxBytes {
+			chunks = append(chunks, chunkLines(content[start:boundary], maxBytes)...)
+			start = boundary
+		}
+		last = boundary
+	}
+	if start < len(content) {
+		chunks = append(chunks, content[start:])
+	}
+	return chunks
+}
+
+// chunkLines packs whole lines into chunks of at most maxBytes
+func chunkLines(content []byte, maxBytes int) [][]byte {
+	var chunks [][]byte
+	start, end := 0, 0
+	for end < len(content) {
+		lineEnd := len(content)
+		if next := bytes.IndexByte(content[end:], '\n'); next >= 0 {
+			lineEnd = end + next + 1
+		}
+		if lineEnd-start > maxBytes && end > start {
+			chunks = append(chunks, content[start:end])
+			start = end
+		}
+		end = lineEnd
+	}
+	if start < len(content) {
+		chunks = append(chunks, content[start:])
+	}
+	return chunks
+}
+
+// symbolBoundaries returns the offsets where top-level declarations start, including
+// their doc comments and decorators, in increasing order. It returns nil for
+// unsupported languages and Go files that do not parse.
+func symbolBoundaries(content []byte, relativePath string) []int {
+	switch strings.ToLower(filepath.Ext(relativePath)) {
+	case ".go":
+		return goDeclBoundaries(content, relativePath)
+	case ".py":
+		return pythonDeclBoundaries(content)
+	}
+	return nil
+}
+
+// goDeclBoundaries finds the start of each top-level Go declaration
+func goDeclBoundaries(content []byte, relativePath string) []int {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Base(relativePath), content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	boundaries := []int{}
+	for _, decl := range file.Decls {
+		pos := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				pos = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				pos = d.Doc.Pos()
+			}
+		}
+		offset := fset.Position(pos).Offset
+		// Start at the beginning of the line, keeping any indentation with the declaration
+		offset = bytes.LastIndexByte(content[:offset], '\n') + 1
+		if offset > 0 {
+			boundaries = append(boundaries, offset)
+		}
+	}
+	return boundaries
+}
+
+// pythonDeclBoundaries finds unindented def, async def and class statements, starting
+// at their decorators
+func pythonDeclBoundaries(content []byte) []int {
+	boundaries := []int{}
+	decorated := false
+	offset := 0
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		switch {
+		case bytes.HasPrefix(line, []byte("@")):
+			if !decorated && offset > 0 {
+				boundaries = append(boundaries, offset)
+			}
+			decorated = true
+		case bytes.HasPrefix(line, []byte("def ")) || bytes.HasPrefix(line, []byte("async def ")) || bytes.HasPrefix(line, []byte("class ")):
+			if !decorated && offset > 0 {
+				boundaries = append(boundaries, offset)
+			}
+			decorated = false
+		case len(bytes.TrimSpace(line)) > 0:
+			decorated = false
+		}
+		offset += len(line)
+	}
+	return boundaries
+}
@@ -0,0 +1,91 @@
+// File: src/cmd/chunks_test.go
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestChunkContent checks where chunks break with and without symbol boundaries
+func TestChunkContent(t *testing.T) {
+	goSource := "package p\n\n" +
+		"// First does one thing.\nfunc First() {\n\tprintln(1)\n}\n\n" +
+		"func Second() {\n\tprintln(2)\n}\n\n" +
+		"type T struct {\n\tA int\n}\n"
+	python := "import os\n\n" +
+		"@decorator\ndef first():\n    return 1\n\n" +
+		"class Second:\n    def method(self):\n        return 2\n"
+
+	cases := []struct {
+		name           string
+		path           string
+		content        string
+		maxBytes       int
+		respectSymbols bool
+		expected       []string
+	}{
+		{"Fits", "a.go", goSource, 1000, true, []string{goSource}},
+		{"Go Declarations", "a.go", goSource, 60, true, []string{
+			"package p\n\n",
+			"// First does one thing.\nfunc First() {\n\tprintln(1)\n}\n\n",
+			"func Second() {\n\tprintln(2)\n}\n\ntype T struct {\n\tA int\n}\n",
+		}},
+		{"Go Lines", "a.go", goSource, 60, false, []string{
+			"package p\n\n// First does one thing.\nfunc First() {\n",
+			"\tprintln(1)\n}\n\nfunc Second() {\n\tprintln(2)\n}\n\n",
+			"type T struct {\n\tA int\n}\n",
+		}},
+		{"Oversized Declaration", "a.go", goSource, 20, true, []string{
+			"package p\n\n",
+			"// First does one thing.\n", "func First() {\n", "\tprintln(1)\n}\n\n",
+			"func Second() {\n", "\tprintln(2)\n}\n\n",
+			"type T struct {\n", "\tA int\n}\n",
+		}},
+		{"Python Decorators", "a.py", python, 40, true, []string{
+			"import os\n\n",
+			"@decorator\ndef first():\n    return 1\n\n",
+			"class Second:\n    def method(self):\n", "        return 2\n",
+		}},
+		{"Unsupported Language", "a.txt", "one\ntwo\nthree\n", 8, true, []string{"one\ntwo\n", "three\n"}},
+		{"Long Line", "a.txt", "short\n" + strings.Repeat("x", 20) + "\nend\n", 8, false, []string{"short\n", strings.Repeat("x", 20) + "\n", "end\n"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got []string
+			for _, chunk := range chunkContent([]byte(c.content), c.path, c.maxBytes, c.respectSymbols) {
+				got = append(got, string(chunk))
+			}
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("Expected chunks %q, got %q", c.expected, got)
+			}
+		})
+	}
+}
+
+// TestChunkTokensOutput checks the chunk headers and that extract reassembles the file
+func TestChunkTokensOutput(t *testing.T) {
+	source := "package main\n\nfunc a() {\n\tprintln(\"a\")\n}\n\nfunc b() {\n\tprintln(\"b\")\n}\n"
+	repoDir := createFixtureRepo(t, map[string]string{"main.go": source, "small.txt": "small\n"})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, ChunkTokens: 8, ChunkRespectSymbols: true})
+
+	for _, header := range []string{
+		"# BEGIN FILE: main.go chunk=1/3\n\npackage main\n\n\n\n# END FILE: main.go\n",
+		"# BEGIN FILE: main.go chunk=2/3\n\nfunc a() {\n\tprintln(\"a\")\n}\n\n\n\n# END FILE: main.go\n",
+		"# BEGIN FILE: main.go chunk=3/3\n\nfunc b() {\n",
+		"# BEGIN FILE: small.txt\n\n",
+	} {
+		if !strings.Contains(output, header) {
+			t.Errorf("Expected %q in the output, got:\n%s", header, output)
+		}
+	}
+
+	extracted, err := parseCombinedOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if len(extracted) != 2 || extracted[0].path != "main.go" || string(extracted[0].content) != source {
+		t.Errorf("Expected extract to reassemble main.go, got %+v", extracted)
+	}
+}
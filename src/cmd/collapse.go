@@ -0,0 +1,267 @@
+// File: src/cmd/collapse.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// collapsedItems is the marker left in place of the elements -collapse-arrays drops
+func collapsedItems(dropped int) string {
+	if dropped == 1 {
+		return `"...": "1 more item"`
+	}
+	return fmt.Sprintf(`"...": "%d more items"`, dropped)
+}
+
+// collapseJSONArrays keeps the first keep elements of every longer array in a JSON
+// document and replaces the rest with an object holding the marker, so the result
+// is still valid JSON. The original formatting is kept. Invalid JSON is returned
+// unchanged.
+func collapseJSONArrays(content []byte, keep int) []byte {
+	if !json.Valid(content) {
+		return content
+	}
+	c := jsonCollapser{data: content, keep: keep}
+	c.whitespace()
+	c.value(true)
+	c.out.Write(content[c.pos:])
+	return c.out.Bytes()
+}
+
+// jsonCollapser copies a valid JSON document to out, dropping array elements as it goes
+type jsonCollapser struct {
+	data []byte
+	pos  int
+	keep int
+	out  bytes.Buffer
+}
+
+// whitespace skips the whitespace at pos and returns it
+func (c *jsonCollapser) whitespace() []byte {
+	start := c.pos
+	for c.pos < len(c.data) && strings.IndexByte(" \t\r\n", c.data[c.pos]) >= 0 {
+		c.pos++
+	}
+	return c.data[start:c.pos]
+}
+
+// value reads the value at pos, copying it to out when emit is set
+func (c *jsonCollapser) value(emit bool) {
+	write := func(b []byte) {
+		if emit {
+			c.out.Write(b)
+		}
+	}
+	switch c.data[c.pos] {
+	case '{':
+		write(c.data[c.pos : c.pos+1])
+		c.pos++
+		for {
+			write(c.whitespace())
+			if c.data[c.pos] == '}' {
+				break
+			}
+			c.value(emit) // the key
+			write(c.whitespace())
+			write(c.data[c.pos : c.pos+1]) // the colon
+			c.pos++
+			write(c.whitespace())
+			c.value(emit)
+			write(c.whitespace())
+			if c.data[c.pos] == ',' {
+				write(c.data[c.pos : c.pos+1])
+				c.pos++
+			}
+		}
+		write(c.data[c.pos : c.pos+1])
+		c.pos++
+	case '[':
+		c.array(emit)
+	case '"':
+		start := c.pos
+		for c.pos++; c.data[c.pos] != '"'; c.pos++ {
+			if c.data[c.pos] == '\\' {
+				c.pos++
+			}
+		}
+		c.pos++
+		write(c.data[start:c.pos])
+	default:
+		start := c.pos
+		for c.pos < len(c.data) && strings.IndexByte(",}] \t\r\n", c.data[c.pos]) < 0 {
+			c.pos++
+		}
+		write(c.data[start:c.pos])
+	}
+}
+
+// array reads the array at pos. Elements past keep are read without being copied,
+// and the marker takes their place, spaced like the first element it replaces.
+func (c *jsonCollapser) array(emit bool) {
+	write := func(b []byte) {
+		if emit {
+			c.out.Write(b)
+		}
+	}
+	write(c.data[c.pos : c.pos+1])
+	c.pos++
+	var gap, trail []byte
+	dropped := 0
+	for i := 0; ; i++ {
+		space := c.whitespace()
+		if i == c.keep {
+			gap = space
+		}
+		if c.data[c.pos] == ']' {
+			trail = space
+			break
+		}
+		kept := i < c.keep
+		if kept {
+			write(space)
+		} else {
+			dropped++
+		}
+		c.value(emit && kept)
+		space = c.whitespace()
+		if c.data[c.pos] != ',' {
+			trail = space
+			break
+		}
+		c.pos++
+		// The comma after the last kept element goes before the marker instead
+		if i+1 < c.keep {
+			write(space)
+			write([]byte{','})
+		}
+	}
+	if dropped > 0 {
+		write([]byte(","))
+		write(gap)
+		write([]byte("{" + collapsedItems(dropped) + "}"))
+	}
+	write(trail)
+	write(c.data[c.pos : c.pos+1])
+	c.pos++
+}
+
+// collapseYAMLSequences keeps the first keep items of every longer block sequence in
+// a YAML document and replaces the rest with a single item holding the marker. Items
+// are found by indentation alone, so flow sequences ([a, b]) are left as they are,
+// and so are the bodies of block scalars (| and >), which are text.
+func collapseYAMLSequences(content []byte, keep int) []byte {
+	var lines []yamlLine
+	scalarIndent := -1
+	for _, text := range strings.SplitAfter(string(content), "\n") {
+		if scalarIndent >= 0 && !yamlBlank(text) && yamlIndent(text) <= scalarIndent {
+			scalarIndent = -1
+		}
+		lines = append(lines, yamlLine{text: text, scalar: scalarIndent >= 0})
+		if indent, ok := yamlBlockScalar(text); ok && scalarIndent < 0 {
+			scalarIndent = indent
+		}
+	}
+	var out strings.Builder
+	for _, line := range collapseYAMLLines(lines, keep) {
+		out.WriteString(line.text)
+	}
+	return []byte(out.String())
+}
+
+// yamlLine is a line of a YAML document; scalar is set for the lines of a block
+// scalar's body
+type yamlLine struct {
+	text   string
+	scalar bool
+}
+
+// Matches a line whose value starts a block scalar, such as "key: |" or "- >-"
+var yamlBlockScalarPattern = regexp.MustCompile(`(?:^|:|-)[ \t]+[|>][1-9+-]{0,2}[ \t]*(?:#.*)?\r?\n?$`)
+
+// yamlBlockScalar reports whether line starts a block scalar, and the column of the
+// key or dash that holds it; the body is every following line indented past it
+func yamlBlockScalar(line string) (int, bool) {
+	if !yamlBlockScalarPattern.MatchString(line) {
+		return 0, false
+	}
+	column := yamlIndent(line)
+	// In "- key: |" the scalar belongs to the key, not the dash
+	for rest := line[column:]; strings.HasPrefix(rest, "- ") && strings.Contains(rest, ":"); rest = line[column:] {
+		column += 2 + yamlIndent(rest[2:])
+	}
+	return column, true
+}
+
+// collapseYAMLLines collapses the sequences in lines, including those nested in the
+// items that are kept
+func collapseYAMLLines(lines []yamlLine, keep int) []yamlLine {
+	var out []yamlLine
+	for i := 0; i < len(lines); {
+		indent, ok := yamlSequenceItem(lines[i])
+		if !ok {
+			out = append(out, lines[i])
+			i++
+			continue
+		}
+
+		// An item runs until the next line that is not blank and not indented past
+		// its dash. Blank lines between items stay with the item before them.
+		var items [][]yamlLine
+		for i < len(lines) {
+			if next, ok := yamlSequenceItem(lines[i]); !ok || next != indent {
+				break
+			}
+			end := i + 1
+			for end < len(lines) && (yamlBlank(lines[end].text) || yamlIndent(lines[end].text) > indent) {
+				end++
+			}
+			for end > i+1 && yamlBlank(lines[end-1].text) {
+				end--
+			}
+			blanks := end
+			for blanks < len(lines) && yamlBlank(lines[blanks].text) {
+				blanks++
+			}
+			if blanks < len(lines) {
+				if next, ok := yamlSequenceItem(lines[blanks]); ok && next == indent {
+					end = blanks
+				}
+			}
+			items = append(items, lines[i:end])
+			i = end
+		}
+
+		for _, item := range items[:min(keep, len(items))] {
+			out = append(out, item[0])
+			out = append(out, collapseYAMLLines(item[1:], keep)...)
+		}
+		if dropped := len(items) - keep; dropped > 0 {
+			out = append(out, yamlLine{text: strings.Repeat(" ", indent) + "- " + collapsedItems(dropped) + "\n"})
+		}
+	}
+	return out
+}
+
+// yamlSequenceItem reports whether line starts a block sequence item, and the column
+// of its dash
+func yamlSequenceItem(line yamlLine) (int, bool) {
+	if line.scalar {
+		return 0, false
+	}
+	trimmed := strings.TrimRight(line.text[yamlIndent(line.text):], "\r\n")
+	return yamlIndent(line.text), trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+}
+
+// yamlIndent counts the spaces at the start of line
+func yamlIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// yamlBlank reports whether line holds only whitespace
+func yamlBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
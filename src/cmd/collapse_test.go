@@ -0,0 +1,145 @@
+// File: src/cmd/collapse_test.go
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestCollapseJSONArrays checks that long arrays keep their first elements and a
+// marker, stay valid JSON and keep their formatting
+func TestCollapseJSONArrays(t *testing.T) {
+	input := `{
+  "users": [
+    {"id": 1, "tags": ["a", "b", "c"]},
+    {"id": 2, "tags": []},
+    {"id": 3, "name": "three"},
+    {"id": 4, "name": "fo]ur"}
+  ],
+  "short": [1, 2],
+  "inline": [1, 2, 3, 4, 5]
+}
+`
+	expected := `{
+  "users": [
+    {"id": 1, "tags": ["a", "b", {"...": "1 more item"}]},
+    {"id": 2, "tags": []},
+    {"...": "2 more items"}
+  ],
+  "short": [1, 2],
+  "inline": [1, 2, {"...": "3 more items"}]
+}
+`
+	got := string(collapseJSONArrays([]byte(input), 2))
+	if got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if !json.Valid([]byte(got)) {
+		t.Errorf("Expected the collapsed document to be valid JSON")
+	}
+
+	if got := string(collapseJSONArrays([]byte(`[1, 2`), 1)); got != `[1, 2` {
+		t.Errorf("Expected invalid JSON to be left alone, got %q", got)
+	}
+}
+
+// TestCollapseYAMLSequences checks that long block sequences keep their first items,
+// including nested ones, and end with a marker item, while block scalar bodies that
+// look like sequences are left alone
+func TestCollapseYAMLSequences(t *testing.T) {
+	input := `users:
+  - id: 1
+    tags:
+      - a
+      - b
+      - c
+
+  - id: 2
+  - id: 3
+flow: [1, 2, 3]
+top:
+- x
+- y
+`
+	expected := `users:
+  - id: 1
+    tags:
+      - a
+      - b
+      - "...": "1 more item"
+
+  - id: 2
+  - "...": "1 more item"
+flow: [1, 2, 3]
+top:
+- x
+- y
+`
+	if got := string(collapseYAMLSequences([]byte(input), 2)); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	input = `notes: |
+  - one
+  - two
+  - three
+steps:
+  - run: >-
+      - not
+      - a
+      - list
+    with:
+      - a
+      - b
+      - c
+  - |
+    - x
+    - y
+    - z
+  - last
+`
+	expected = `notes: |
+  - one
+  - two
+  - three
+steps:
+  - run: >-
+      - not
+      - a
+      - list
+    with:
+      - a
+      - b
+      - "...": "1 more item"
+  - |
+    - x
+    - y
+    - z
+  - "...": "1 more item"
+`
+	if got := string(collapseYAMLSequences([]byte(input), 2)); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+// TestCollapseArraysTransform checks that -collapse-arrays only touches JSON and YAML files
+func TestCollapseArraysTransform(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"seed.json": "[1, 2, 3]\n",
+		"seed.yml":  "- 1\n- 2\n- 3\n",
+		"notes.txt": "- 1\n- 2\n- 3\n",
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, CollapseArrays: 1})
+
+	for _, expected := range []string{
+		"# BEGIN FILE: seed.json\n\n[1, {\"...\": \"2 more items\"}]\n",
+		"# BEGIN FILE: seed.yml\n\n- 1\n- \"...\": \"2 more items\"\n",
+		"# BEGIN FILE: notes.txt\n\n- 1\n- 2\n- 3\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in the output, got:\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+// File: src/cmd/config.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config holds the settings for a single Colligo run. The JSON names match the
+// command-line flags, so a config file uses the same vocabulary as the CLI.
+type Config struct {
+	RepoPath      string `json:"repo"`
+	OutputFile    string `json:"output"`
+	LogLevel      string `json:"log-level"`
+	MaxTokens     int    `json:"max-tokens"`
+	FitBudget     bool   `json:"fit-budget"`
+	Summary       bool   `json:"summary"`
+	StatsFile     string `json:"stats-file"`
+	SizeHistogram bool   `json:"emit-size-histogram"`
+	Manifest      string `json:"manifest"`
+	Head          int    `json:"head"`
+	StripComments bool   `json:"strip-comments"`
+	PreRunCmd     string `json:"pre-run-cmd"`
+	PostRunCmd    string `json:"post-run-cmd"`
+	Format        string `json:"format"`
+	// BlockSeparator is nil unless -block-separator was given
+	BlockSeparator     *string  `json:"block-separator"`
+	ExcludePatterns    []string `json:"exclude-pattern"`
+	ForceInclude       []string `json:"force-include"`
+	ExcludeCompiled    bool     `json:"exclude-compiled"`
+	CompiledExtensions []string `json:"compiled-extension"`
+	// PerFileTimeoutMultiplier scales the expected read time of each file into its timeout
+	PerFileTimeoutMultiplier float64 `json:"per-file-timeout-multiplier"`
+	AutoRoot                 bool    `json:"auto-root"`
+	PreserveHardlinks        bool    `json:"preserve-hardlinks"`
+	PerFileTokenLimit        int     `json:"per-file-token-limit"`
+	PerFileTruncateStrategy  string  `json:"per-file-truncate-strategy"`
+	// Formats, when set, writes one sibling output per format instead of -format
+	Formats []string `json:"formats"`
+	// PathsFile lists the directory prefixes to include; everything else is pruned
+	PathsFile string `json:"paths-file"`
+	// EncodePaths percent-encodes the paths in the txt markers
+	EncodePaths bool `json:"encode-paths"`
+	// DelimiterCollision decides what happens when file content contains txt markers
+	DelimiterCollision string `json:"delimiter-collision"`
+	// EmitGoDocSummaries starts each Go file with a summary of its package doc
+	EmitGoDocSummaries bool `json:"emit-go-doc-summaries"`
+	// Metadata adds size, language and checksum lines to each txt file block
+	Metadata bool `json:"metadata"`
+	// MaxFileDepth is nil unless -max-file-depth was given
+	MaxFileDepth *int `json:"max-file-depth"`
+	// AlwaysInclude lists base names of files the hidden-file filter never excludes
+	AlwaysInclude []string `json:"always-include"`
+	// MaxWalkEntries aborts the walk after visiting this many entries; 0 disables it
+	MaxWalkEntries int `json:"max-walk-entries"`
+	// SummaryTopN lists the N largest, newest and longest files in the summary
+	SummaryTopN int `json:"summary-top-n"`
+	// TransformOrder reorders the content transforms; nil keeps the default order
+	TransformOrder []string `json:"transform-order"`
+	// ReadAhead is how many files a background goroutine reads ahead of the writer
+	ReadAhead int `json:"read-ahead"`
+	// DocsOnly includes only documentation files (.md, .rst, .txt, .adoc)
+	DocsOnly bool `json:"docs-only"`
+	// MaxDepth is nil unless -max-depth was given
+	MaxDepth *int `json:"max-depth"`
+	// DepthMap overrides MaxDepth for individual top-level directories
+	DepthMap map[string]int `json:"depth-map"`
+	// VerifyOutput reads each output back after writing and checks its SHA-256
+	VerifyOutput bool `json:"verify-output"`
+	// SanitizeUTF8 replaces or strips invalid UTF-8 in file content; empty leaves it alone
+	SanitizeUTF8 string `json:"sanitize-utf8"`
+	// SkipDotFilesWithExtensions lists the extensions of hidden files to skip; other
+	// hidden files are included, while hidden directories are still pruned
+	SkipDotFilesWithExtensions []string `json:"skip-dot-files-with-extensions"`
+	// PreferFileOverSymlink writes a placeholder for symlinks to included files
+	PreferFileOverSymlink bool `json:"prefer-file-over-symlink"`
+	// OutputLock takes an advisory lock on each output file before writing it
+	OutputLock bool `json:"output-lock"`
+	// LockTimeout bounds the wait for -output-lock, 30s by default; 0 waits indefinitely
+	LockTimeout duration `json:"lock-timeout"`
+	// JSONLineOffsets adds the byte offset of each line to JSON file records
+	JSONLineOffsets bool `json:"json-line-offsets"`
+	// GitDiffStat adds each file's line changes since HEAD~1 to its txt header
+	GitDiffStat bool `json:"git-diff-stat"`
+	// RAGMode adds a front matter block to each txt file and escapes colliding lines
+	RAGMode bool `json:"rag-mode"`
+	// EmitFunctionList lists the functions defined by each Go and Python file in its txt header
+	EmitFunctionList bool `json:"emit-function-list"`
+	// WarnFileTokens logs a warning for each file estimated above N tokens; 0 disables it
+	WarnFileTokens int `json:"warn-file-tokens"`
+	// RewriteMDLinks rewrites relative Markdown links to heading anchors, or to
+	// RepoURL when it is set
+	RewriteMDLinks bool   `json:"rewrite-md-links"`
+	RepoURL        string `json:"repo-url"`
+	// RepoStatsHeader writes an overview of the selected files before the first txt file
+	RepoStatsHeader bool `json:"repo-stats-header"`
+	// SeenStore persists the hashes of emitted files so later runs write placeholders for them
+	SeenStore string `json:"seen-store"`
+	// ExcludeIfContains replaces files containing any of these strings with a placeholder
+	ExcludeIfContains []string `json:"exclude-if-contains-string"`
+	// IgnoreCase matches -exclude-if-contains-string case-insensitively
+	IgnoreCase bool `json:"ignore-case"`
+	// IOProfile tunes the -read-ahead and -buffer-size defaults for the storage type
+	IOProfile string `json:"io-profile"`
+	// InlineImages embeds image files in HTML output as data URIs
+	InlineImages bool `json:"inline-images"`
+	// OutputChecksum ends text outputs with the SHA-256 of everything before it
+	OutputChecksum bool `json:"output-checksum"`
+	// EmitDependencyGraph ends txt output with a DOT graph of the imports between Go packages
+	EmitDependencyGraph bool `json:"emit-dependency-graph"`
+	// ChunkTokens splits txt blocks of larger files into chunks of about N tokens
+	ChunkTokens int `json:"chunk-tokens"`
+	// ChunkRespectSymbols breaks chunks at top-level declarations where possible
+	ChunkRespectSymbols bool `json:"chunk-respect-symbols"`
+	// HeaderCommentStyle is the comment syntax of the txt BEGIN/END FILE markers and
+	// the other comment lines of txt output
+	HeaderCommentStyle string `json:"header-comment-style"`
+	// ExcludeMigrations drops framework-generated migrations, except the latest
+	// KeepLatestMigrations of each migration history
+	ExcludeMigrations    bool `json:"exclude-migrations"`
+	KeepLatestMigrations int  `json:"keep-latest-migrations"`
+	// PromptTemplate wraps text outputs in the template file, in place of its {context}
+	// placeholder
+	PromptTemplate string `json:"prompt-template"`
+	// EmitChangeFrequency adds the number of commits that touched each file to its
+	// txt header
+	EmitChangeFrequency bool `json:"emit-change-frequency"`
+	// Sort orders the files; the walk order is kept when it is empty
+	Sort string `json:"sort"`
+	// CollapseArrays keeps the first N elements of longer arrays in JSON and YAML files
+	CollapseArrays int `json:"collapse-arrays"`
+	// BufferSize sizes the output writers and file readers
+	BufferSize byteSize `json:"buffer-size"`
+
+	// ConfigFile is where the settings were loaded from; it is not itself configurable
+	ConfigFile string `json:"-"`
+}
+
+// defaultConfig returns the settings used when neither a config file nor a flag sets them
+func defaultConfig() *Config {
+	return &Config{
+		RepoPath:                   ".",
+		LogLevel:                   "info",
+		Format:                     "txt",
+		PerFileTruncateStrategy:    "head",
+		BufferSize:                 defaultBufferSize,
+		DelimiterCollision:         "warn",
+		HeaderCommentStyle:         "hash",
+		MaxWalkEntries:             1_000_000,
+		LockTimeout:                duration(defaultLockTimeout),
+		AlwaysInclude:              []string{".gitignore", "go.mod", "package.json", "README.md"},
+		SkipDotFilesWithExtensions: []string{".swp", ".swo", ".tmp", ".log", ".bak", ".env", ".DS_Store"},
+	}
+}
+
+// registerFlags defines the command-line flags on fs, using the current values in cfg
+// as their defaults
+func registerFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&cfg.ConfigFile, "config", "", "Load settings from this .json config file; flags given on the command line take precedence, and repeatable ones replace its lists")
+	fs.StringVar(&cfg.RepoPath, "repo", cfg.RepoPath, "Path to your local repository")
+	fs.StringVar(&cfg.OutputFile, "output", cfg.OutputFile, "Output file name (optional)")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Set the logging level (debug, info, warn, error)")
+	fs.IntVar(&cfg.MaxTokens, "max-tokens", cfg.MaxTokens, "Maximum estimated tokens to include (0 for no limit)")
+	fs.BoolVar(&cfg.FitBudget, "fit-budget", cfg.FitBudget, "Select the most relevant files that fit -max-tokens instead of stopping at the limit")
+	fs.BoolVar(&cfg.Summary, "summary", cfg.Summary, "Append a summary section to the output")
+	fs.StringVar(&cfg.StatsFile, "stats-file", cfg.StatsFile, "Write run statistics as JSON to this file (optional)")
+	fs.BoolVar(&cfg.SizeHistogram, "emit-size-histogram", cfg.SizeHistogram, "Include a file size histogram in the summary and stats file")
+	fs.StringVar(&cfg.Manifest, "manifest", cfg.Manifest, "Read the files to include, with per-file directives, from this manifest (- for stdin)")
+	fs.IntVar(&cfg.Head, "head", cfg.Head, "Include only the first N lines of each file (0 for all)")
+	fs.BoolVar(&cfg.StripComments, "strip-comments", cfg.StripComments, "Remove whole-line comments from source files")
+	fs.StringVar(&cfg.PreRunCmd, "pre-run-cmd", cfg.PreRunCmd, "Shell command to run before walking the repository; a failure aborts the run")
+	fs.StringVar(&cfg.PostRunCmd, "post-run-cmd", cfg.PostRunCmd, "Shell command to run after the output is written, with COLLIGO_OUTPUT set to the output path")
+	fs.StringVar(&cfg.Format, "format", cfg.Format, "Output format ("+strings.Join(formatNames(), ", ")+")")
+	fs.Func("block-separator", `Text placed between file blocks in txt output, replacing the default blank lines; supports \n and \t escapes (e.g. "---\n")`, func(value string) error {
+		separator := unescapeSeparator(value)
+		cfg.BlockSeparator = &separator
+		return nil
+	})
+	fs.Var(&stringListFlag{list: &cfg.ExcludePatterns}, "exclude-pattern", "Glob pattern for paths to exclude, matched against the relative path and base name (repeatable)")
+	fs.Var(&stringListFlag{list: &cfg.ForceInclude}, "force-include", "Relative file path to include regardless of the path exclusion rules (repeatable)")
+	fs.BoolVar(&cfg.ExcludeCompiled, "exclude-compiled", cfg.ExcludeCompiled, "Exclude compiled artifacts (.o, .a, .pyc, .class, ...) by extension")
+	fs.Var(&stringListFlag{list: &cfg.CompiledExtensions}, "compiled-extension", "Additional extension treated as compiled by -exclude-compiled (repeatable)")
+	fs.Float64Var(&cfg.PerFileTimeoutMultiplier, "per-file-timeout-multiplier", cfg.PerFileTimeoutMultiplier, "Abort a file read after N times its expected duration, based on a moving average of read throughput (0 disables)")
+	fs.BoolVar(&cfg.AutoRoot, "auto-root", cfg.AutoRoot, "Use the nearest ancestor of -repo containing .git, go.mod, package.json, .colligo.yaml or .colligo.json as the repository")
+	fs.BoolVar(&cfg.PreserveHardlinks, "preserve-hardlinks", cfg.PreserveHardlinks, "Write hardlinked files once and replace later links with a placeholder (Unix only)")
+	fs.IntVar(&cfg.PerFileTokenLimit, "per-file-token-limit", cfg.PerFileTokenLimit, "Truncate any file whose estimated tokens exceed N (0 for no limit)")
+	fs.StringVar(&cfg.PerFileTruncateStrategy, "per-file-truncate-strategy", cfg.PerFileTruncateStrategy, "How to truncate files over -per-file-token-limit ("+strings.Join(truncateStrategies, ", ")+")")
+	fs.Func("formats", "Comma-separated formats to write in one run, e.g. txt,markdown,json; each goes to the output base plus the format's extension", func(value string) error {
+		cfg.Formats = parseCommaList(value)
+		return nil
+	})
+	fs.StringVar(&cfg.PathsFile, "paths-file", cfg.PathsFile, "File listing directory prefixes to include, one per line; everything outside them is pruned from the walk")
+	fs.BoolVar(&cfg.EncodePaths, "encode-paths", cfg.EncodePaths, "Percent-encode paths in txt BEGIN/END FILE markers and record the original in an original-path= field")
+	fs.StringVar(&cfg.DelimiterCollision, "delimiter-collision", cfg.DelimiterCollision, "What to do when file content contains the txt BEGIN/END FILE markers ("+strings.Join(delimiterCollisionModes, ", ")+"); auto picks randomized markers")
+	fs.BoolVar(&cfg.EmitGoDocSummaries, "emit-go-doc-summaries", cfg.EmitGoDocSummaries, "Start each Go file with a // SUMMARY: comment holding its package documentation")
+	fs.BoolVar(&cfg.Metadata, "metadata", cfg.Metadata, "Add size, language and sha256 comment lines after each txt BEGIN FILE marker")
+	fs.Func("max-file-depth", "Include only files with at most N directories in their relative path; deeper directories are still walked", func(value string) error {
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			return fmt.Errorf("expected a non-negative integer, got %q", value)
+		}
+		cfg.MaxFileDepth = &depth
+		return nil
+	})
+	fs.Func("always-include", "Comma-separated base names of files the hidden-file filter never excludes; explicit excludes and -paths-file still apply (default \""+strings.Join(cfg.AlwaysInclude, ",")+"\"; empty to disable)", func(value string) error {
+		cfg.AlwaysInclude = parseCommaList(value)
+		return nil
+	})
+	fs.IntVar(&cfg.MaxWalkEntries, "max-walk-entries", cfg.MaxWalkEntries, "Abort if the walk visits more than N files and directories, included or not (0 for no limit)")
+	fs.IntVar(&cfg.SummaryTopN, "summary-top-n", cfg.SummaryTopN, "List the N largest, most recently modified and longest files in the summary and stats file (0 to skip)")
+	fs.Func("transform-order", "Comma-separated order of the content transforms (default \""+strings.Join(transformNames(), ",")+"\"); transforms left out run afterwards in the default order", func(value string) error {
+		cfg.TransformOrder = parseCommaList(value)
+		return nil
+	})
+	fs.IntVar(&cfg.ReadAhead, "read-ahead", cfg.ReadAhead, "Read up to N files in the background while earlier ones are written (0 reads each file when it is written)")
+	fs.BoolVar(&cfg.DocsOnly, "docs-only", cfg.DocsOnly, "Include only documentation files (.md, .rst, .txt, .adoc)")
+	fs.Func("max-depth", "Do not enter directories more than N levels below the repository (0 keeps only top-level files)", func(value string) error {
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			return fmt.Errorf("expected a non-negative integer, got %q", value)
+		}
+		cfg.MaxDepth = &depth
+		return nil
+	})
+	fs.Func("depth-map", `Per top-level directory max depths, e.g. "docs=1,src=20"; other directories use -max-depth`, func(value string) error {
+		depths, err := parseDepthMap(value)
+		if err != nil {
+			return err
+		}
+		cfg.DepthMap = depths
+		return nil
+	})
+	fs.BoolVar(&cfg.VerifyOutput, "verify-output", cfg.VerifyOutput, "Read each output file back after writing and fail if its SHA-256 differs from what was written")
+	fs.StringVar(&cfg.SanitizeUTF8, "sanitize-utf8", cfg.SanitizeUTF8, "Fix invalid UTF-8 in file content by replacing each bad sequence with U+FFFD or dropping it ("+strings.Join(sanitizeUTF8Modes, ", ")+")")
+	fs.Func("skip-dot-files-with-extensions", "Comma-separated extensions of hidden files to skip; other hidden files are included, except .env files and known credential files such as .npmrc and .netrc (default \""+strings.Join(cfg.SkipDotFilesWithExtensions, ",")+"\")", func(value string) error {
+		cfg.SkipDotFilesWithExtensions = parseCommaList(value)
+		return nil
+	})
+	fs.BoolVar(&cfg.PreferFileOverSymlink, "prefer-file-over-symlink", cfg.PreferFileOverSymlink, "Replace a symlink with a placeholder when its target is also an included file")
+	fs.BoolVar(&cfg.OutputLock, "output-lock", cfg.OutputLock, "Take an exclusive advisory lock on each output file before writing, waiting for other runs that hold it")
+	fs.Var(&cfg.LockTimeout, "lock-timeout", "How long -output-lock waits for the lock before giving up, e.g. 10s or 2m (0 waits indefinitely)")
+	fs.BoolVar(&cfg.JSONLineOffsets, "json-line-offsets", cfg.JSONLineOffsets, "Add a lineOffsets array with the byte offset where each line starts to every JSON file record")
+	fs.BoolVar(&cfg.GitDiffStat, "git-diff-stat", cfg.GitDiffStat, "Add insertions=N deletions=N to each txt file header, counting changes since HEAD~1 (requires git and a git repository)")
+	fs.BoolVar(&cfg.RAGMode, "rag-mode", cfg.RAGMode, "Tailor txt output for retrieval pipelines: add a front matter block with path, language, size, sha256 and whether the content was transformed, and escape lines that look like delimiters")
+	fs.BoolVar(&cfg.EmitFunctionList, "emit-function-list", cfg.EmitFunctionList, "Add a \"Functions:\" comment line naming the functions and methods each .go and .py file defines after its txt header")
+	fs.IntVar(&cfg.WarnFileTokens, "warn-file-tokens", cfg.WarnFileTokens, "Log a warning for each file estimated at more than N tokens, without changing what is included (0 disables)")
+	fs.BoolVar(&cfg.RewriteMDLinks, "rewrite-md-links", cfg.RewriteMDLinks, "Rewrite relative links in Markdown files to the linked file's heading anchor, or to its URL under -repo-url")
+	fs.StringVar(&cfg.RepoURL, "repo-url", cfg.RepoURL, "Base URL of the repository's files, e.g. https://github.com/org/repo/blob/main, used by -rewrite-md-links")
+	fs.BoolVar(&cfg.RepoStatsHeader, "repo-stats-header", cfg.RepoStatsHeader, "Start txt output with a comment block giving the repository name and the number of files, directories, bytes and most common extensions")
+	fs.StringVar(&cfg.SeenStore, "seen-store", cfg.SeenStore, "File of SHA-256 hashes of content emitted by earlier runs; files already in it, or repeated within the run, are written as placeholders, and new hashes are added after the run")
+	fs.Var(&stringListFlag{list: &cfg.ExcludeIfContains}, "exclude-if-contains-string", "Replace any file whose content contains this literal string with a placeholder; this wins over -force-include (repeatable)")
+	fs.BoolVar(&cfg.IgnoreCase, "ignore-case", cfg.IgnoreCase, "Match -exclude-if-contains-string case-insensitively")
+	fs.StringVar(&cfg.IOProfile, "io-profile", cfg.IOProfile, "Tune the read-ahead and buffer size defaults for the storage holding the repository: local (-read-ahead 0, -buffer-size 4KB) or network (-read-ahead 16, -buffer-size 1MB); explicit non-default values still win")
+	fs.BoolVar(&cfg.InlineImages, "inline-images", cfg.InlineImages, "Embed image files in -format=html output as base64 data URIs instead of a binary placeholder")
+	fs.BoolVar(&cfg.OutputChecksum, "output-checksum", cfg.OutputChecksum, "End txt, compact, markdown and org output with an \"OUTPUT-SHA256: <hex>\" comment line holding the SHA-256 of everything before it")
+	fs.BoolVar(&cfg.EmitDependencyGraph, "emit-dependency-graph", cfg.EmitDependencyGraph, "End txt output with a DEPENDENCY GRAPH section: a DOT graph of the imports between the Go packages included")
+	fs.IntVar(&cfg.ChunkTokens, "chunk-tokens", cfg.ChunkTokens, "Split each file larger than about N tokens into several txt blocks marked chunk=i/n, breaking between lines (0 disables)")
+	fs.BoolVar(&cfg.ChunkRespectSymbols, "chunk-respect-symbols", cfg.ChunkRespectSymbols, "Break -chunk-tokens chunks of Go and Python files at top-level declarations where possible")
+	fs.StringVar(&cfg.HeaderCommentStyle, "header-comment-style", cfg.HeaderCommentStyle, "Comment syntax of the txt BEGIN/END FILE markers and other comment lines ("+strings.Join(headerCommentStyles, ", ")+"); xml and html close each line with --> and encode -- in paths as -%2D")
+	fs.BoolVar(&cfg.ExcludeMigrations, "exclude-migrations", cfg.ExcludeMigrations, "Exclude generated database migrations (Django, Rails, Prisma, golang-migrate)")
+	fs.IntVar(&cfg.KeepLatestMigrations, "keep-latest-migrations", cfg.KeepLatestMigrations, "Keep the latest N migrations of each migration directory when -exclude-migrations is set")
+	fs.StringVar(&cfg.PromptTemplate, "prompt-template", cfg.PromptTemplate, "Wrap txt, compact, markdown and org output in the prompt in this file, replacing its {context} placeholder, for a ready-to-send prompt")
+	fs.BoolVar(&cfg.EmitChangeFrequency, "emit-change-frequency", cfg.EmitChangeFrequency, "Add a change-count field to each txt BEGIN FILE marker with the number of commits that touched the file")
+	fs.StringVar(&cfg.Sort, "sort", cfg.Sort, "Order of the files in the output ("+strings.Join(sortOrders, ", ")+"); the walk order is kept when unset")
+	fs.IntVar(&cfg.CollapseArrays, "collapse-arrays", cfg.CollapseArrays, "Keep the first N elements of longer arrays in JSON and YAML files, replacing the rest with a \"...\": \"K more items\" marker (0 disables)")
+	fs.Var(&cfg.BufferSize, "buffer-size", "Size of the output write and file read buffers, e.g. 64KB or 1MB")
+}
+
+// findConfigArg returns the value of -config among the command-line arguments, which
+// must be known before the other flags are defined so its settings can become defaults.
+// The arguments are scanned without knowing which flags take values, so only "--"
+// ends the search.
+func findConfigArg(args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return ""
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		if value, ok := strings.CutPrefix(name, "config="); ok {
+			return value
+		}
+		if name == "config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// checkConfigFileName rejects config files that are not JSON by their extension, so a
+// YAML or TOML file gets a clear error instead of a JSON syntax error
+func checkConfigFileName(path string) error {
+	if ext := filepath.Ext(path); !strings.EqualFold(ext, ".json") {
+		return fmt.Errorf("unsupported config file %s: config files must be JSON with a .json extension", filepath.Base(path))
+	}
+	return nil
+}
+
+// loadConfigFile validates a JSON config file against the config schema and applies
+// its settings on top of cfg
+func loadConfigFile(path string, cfg *Config) error {
+	if err := checkConfigFileName(path); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if problems := validateConfigData(data); len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(cfg)
+}
+
+// runValidateConfig implements the validate-config subcommand and returns its exit code
+func runValidateConfig(args []string) int {
+	fs := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	path := fs.String("config", "", "Config file to validate")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *path == "" {
+		fmt.Fprintln(os.Stderr, "validate-config: -config is required")
+		fs.Usage()
+		return 2
+	}
+	if err := checkConfigFileName(*path); err != nil {
+		fmt.Fprintf(os.Stderr, "validate-config: %v\n", err)
+		return 1
+	}
+
+	data, err := os.ReadFile(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "validate-config: %v\n", err)
+		return 1
+	}
+	problems := validateConfigData(data)
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%s is invalid:\n", *path)
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", problem)
+		}
+		return 1
+	}
+	fmt.Printf("%s is valid\n", *path)
+	return 0
+}
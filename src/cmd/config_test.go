@@ -0,0 +1,112 @@
+// File: src/cmd/config_test.go
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFindConfigArg checks that -config is found before the other flags are defined
+func TestFindConfigArg(t *testing.T) {
+	cases := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-config", "a.json", "-repo", "x"}, "a.json"},
+		{[]string{"-repo", "x", "--config=b.json"}, "b.json"},
+		{[]string{"-repo", "x"}, ""},
+		{[]string{"--", "-config", "c.json"}, ""},
+	}
+
+	for _, c := range cases {
+		if got := findConfigArg(c.args); got != c.expected {
+			t.Errorf("findConfigArg(%q) = %q, expected %q", c.args, got, c.expected)
+		}
+	}
+}
+
+// TestConfigFilePrecedence checks that config file values override defaults and flags override the file
+func TestConfigFilePrecedence(t *testing.T) {
+	path := filepath.Join(createTempDir(t, "config"), "colligo.json")
+	if err := os.WriteFile(path, []byte(`{"repo": "from-file", "max-tokens": 500, "exclude-pattern": ["*.log"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg := defaultConfig()
+	if err := loadConfigFile(path, cfg); err != nil {
+		t.Fatalf("Failed to load config file: %v", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	registerFlags(fs, cfg)
+	if err := fs.Parse([]string{"-config", path, "-max-tokens", "900"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	if cfg.RepoPath != "from-file" {
+		t.Errorf("Expected repo from the config file, got %q", cfg.RepoPath)
+	}
+	if cfg.MaxTokens != 900 {
+		t.Errorf("Expected -max-tokens to override the config file, got %d", cfg.MaxTokens)
+	}
+	if len(cfg.ExcludePatterns) != 1 || cfg.ExcludePatterns[0] != "*.log" {
+		t.Errorf("Expected exclude patterns from the config file, got %v", cfg.ExcludePatterns)
+	}
+	if cfg.Format != "txt" {
+		t.Errorf("Expected the default format to survive, got %q", cfg.Format)
+	}
+	if time.Duration(cfg.LockTimeout) != defaultLockTimeout {
+		t.Errorf("Expected the default lock timeout to survive, got %v", time.Duration(cfg.LockTimeout))
+	}
+}
+
+// TestConfigFileListFlags checks that a repeatable flag on the command line replaces the
+// config file's list instead of adding to it
+func TestConfigFileListFlags(t *testing.T) {
+	path := filepath.Join(createTempDir(t, "config"), "colligo.json")
+	if err := os.WriteFile(path, []byte(`{"exclude-pattern": ["*.log", "*.tmp"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg := defaultConfig()
+	if err := loadConfigFile(path, cfg); err != nil {
+		t.Fatalf("Failed to load config file: %v", err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	registerFlags(fs, cfg)
+	if err := fs.Parse([]string{"-exclude-pattern", "*.bak", "-exclude-pattern", "*.orig"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	if got := strings.Join(cfg.ExcludePatterns, ","); got != "*.bak,*.orig" {
+		t.Errorf("Expected the flags to replace the config file's patterns, got %q", got)
+	}
+}
+
+// TestLoadConfigFileRejectsInvalid checks that schema violations and files that are not
+// JSON stop the config from loading
+func TestLoadConfigFileRejectsInvalid(t *testing.T) {
+	dir := createTempDir(t, "config")
+	path := filepath.Join(dir, "colligo.json")
+	if err := os.WriteFile(path, []byte(`{"max-tokns": 10}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	if err := loadConfigFile(path, defaultConfig()); err == nil {
+		t.Errorf("Expected an unknown key to be rejected")
+	}
+
+	for _, name := range []string{"colligo.yaml", "colligo.toml"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("max-tokens: 10\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		if err := loadConfigFile(path, defaultConfig()); err == nil || !strings.Contains(err.Error(), "must be JSON") {
+			t.Errorf("Expected %s to be rejected as not JSON, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// File: src/cmd/delimiters.go
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Values accepted by -delimiter-collision
+var delimiterCollisionModes = []string{"warn", "fail", "escape", "auto"}
+
+// Values accepted by -header-comment-style
+var headerCommentStyles = []string{"hash", "double-slash", "xml", "html"}
+
+// commentDelimiters returns the text that opens and closes a marker line in the
+// given -header-comment-style. XML and HTML share the same comment syntax.
+func commentDelimiters(style string) (open string, close string) {
+	switch style {
+	case "double-slash":
+		return "// ", ""
+	case "xml", "html":
+		return "<!-- ", " -->"
+	}
+	return "# ", ""
+}
+
+// fileMarkers are the strings that open and close every file block in txt output.
+// open starts every comment line in the output's style, including the markers, and
+// suffix ends them, closing the comment for styles that need it.
+type fileMarkers struct {
+	begin  string
+	end    string
+	open   string
+	suffix string
+}
+
+// markersFor returns the txt markers in the comment syntax of style, made unique
+// with token when one is set by -delimiter-collision auto
+func markersFor(style string, token string) fileMarkers {
+	open, close := commentDelimiters(style)
+	if token == "" {
+		return fileMarkers{begin: open + "BEGIN FILE: ", end: open + "END FILE: ", open: open, suffix: close}
+	}
+	return fileMarkers{begin: open + "BEGIN FILE " + token + ": ", end: open + "END FILE " + token + ": ", open: open, suffix: close}
+}
+
+// comment renders text as a comment line in the style of the markers
+func (m fileMarkers) comment(text string) string {
+	return m.open + m.commentText(text) + m.suffix + "\n"
+}
+
+// Undoes commentText for comments that are closed, such as xml and html
+var commentTextUnescaper = strings.NewReplacer("%2D", "-", "%25", "%")
+
+// commentText makes text safe inside a comment. XML comments may not contain "--",
+// so in closed comments the second dash of each pair is percent-encoded, along with
+// any % so the encoding can be undone.
+func (m fileMarkers) commentText(text string) string {
+	if m.suffix == "" || (!strings.Contains(text, "--") && !strings.Contains(text, "%")) {
+		return text
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '%':
+			b.WriteString("%25")
+		case text[i] == '-' && i > 0 && text[i-1] == '-':
+			b.WriteString("%2D")
+		default:
+			b.WriteByte(text[i])
+		}
+	}
+	return b.String()
+}
+
+// uncommentText reverses commentText
+func (m fileMarkers) uncommentText(text string) string {
+	if m.suffix == "" {
+		return text
+	}
+	return commentTextUnescaper.Replace(text)
+}
+
+// Field added to the BEGIN FILE marker of a block whose content was escaped
+const escapedField = " escaped=true"
+
+// collides reports whether any line of content starts with one of the markers
+func (m fileMarkers) collides(content []byte) bool {
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if m.startsLine(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// startsLine reports whether line begins with one of the markers
+func (m fileMarkers) startsLine(line []byte) bool {
+	return bytes.HasPrefix(line, []byte(m.begin)) || bytes.HasPrefix(line, []byte(m.end))
+}
+
+// escape prefixes a comma to every line that starts with a marker, or with commas
+// followed by a marker so the escape can be undone. It reports whether anything changed.
+func (m fileMarkers) escape(content []byte) ([]byte, bool) {
+	var out bytes.Buffer
+	escaped := false
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if m.startsLine(bytes.TrimLeft(line, ",")) {
+			out.WriteByte(',')
+			escaped = true
+		}
+		out.Write(line)
+	}
+	if !escaped {
+		return content, false
+	}
+	return out.Bytes(), true
+}
+
+// unescape reverses escape
+func (m fileMarkers) unescape(content []byte) []byte {
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte(",")) && m.startsLine(bytes.TrimLeft(line, ",")) {
+			line = line[1:]
+		}
+		out.Write(line)
+	}
+	return out.Bytes()
+}
+
+// validateDelimiterCollision checks the value of -delimiter-collision
+func validateDelimiterCollision(mode string) error {
+	if mode == "" || slices.Contains(delimiterCollisionModes, mode) {
+		return nil
+	}
+	return fmt.Errorf("unknown delimiter collision mode %q (supported: %s)", mode, strings.Join(delimiterCollisionModes, ", "))
+}
+
+// validateHeaderCommentStyle checks the value of -header-comment-style
+func validateHeaderCommentStyle(style string) error {
+	if style == "" || slices.Contains(headerCommentStyles, style) {
+		return nil
+	}
+	return fmt.Errorf("unknown header comment style %q (supported: %s)", style, strings.Join(headerCommentStyles, ", "))
+}
+
+// resolveDelimiterCollisions handles the modes of -delimiter-collision that need to
+// see every file before the output is written: fail rejects the run if any content
+// contains a marker, and auto picks a marker token that appears in no content.
+func resolveDelimiterCollisions(logger *slog.Logger, cfg *Config, state *runState, entries []fileEntry) error {
+	if state.delimiterCollision != "fail" && state.delimiterCollision != "auto" {
+		return nil
+	}
+	writesTxt := false
+	for _, target := range outputTargets(cfg) {
+		writesTxt = writesTxt || target.format == "" || target.format == "txt"
+	}
+	if !writesTxt {
+		return nil
+	}
+
+	var candidates []string
+	if state.delimiterCollision == "auto" {
+		for i := 0; i < 4; i++ {
+			token, err := randomToken()
+			if err != nil {
+				return err
+			}
+			candidates = append(candidates, token)
+		}
+	}
+
+	markers := markersFor(cfg.HeaderCommentStyle, "")
+	var colliding []string
+	reader := newFileReader(cfg)
+	for _, entry := range entries {
+		if entry.Note != "" {
+			continue
+		}
+		// Unreadable files are reported when the output is written
+		content, err := reader.read(entry)
+		if err != nil {
+			continue
+		}
+		content = applyTransforms(content, entry)
+		if markers.collides(content) {
+			colliding = append(colliding, entry.RelativePath)
+		}
+		candidates = slices.DeleteFunc(candidates, func(token string) bool {
+			return bytes.Contains(content, []byte(token))
+		})
+	}
+
+	if state.delimiterCollision == "fail" {
+		if len(colliding) > 0 {
+			return fmt.Errorf("file content contains output delimiters: %s", strings.Join(colliding, ", "))
+		}
+		return nil
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("could not find a delimiter token absent from every file")
+	}
+	state.delimiterToken = candidates[0]
+	logger.Info("Using randomized file delimiters", "token", state.delimiterToken, "collidingFiles", len(colliding))
+	return nil
+}
+
+// randomToken returns a random hex string for -delimiter-collision auto
+func randomToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
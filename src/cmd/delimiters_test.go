@@ -0,0 +1,86 @@
+// File: src/cmd/delimiters_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Content that quotes the txt markers, as documentation about Colligo would
+const collidingContent = "Blocks look like this:\n# BEGIN FILE: example.go\n,# END FILE: example.go\nend\n"
+
+// TestMarkersEscapeRoundTrip checks that escaping is reversible, including lines that already start with commas
+func TestMarkersEscapeRoundTrip(t *testing.T) {
+	markers := markersFor("", "")
+	escaped, changed := markers.escape([]byte(collidingContent))
+	if !changed {
+		t.Fatalf("Expected colliding content to be escaped")
+	}
+	if markers.collides(escaped) {
+		t.Errorf("Expected escaped content not to collide, got:\n%s", escaped)
+	}
+	if got := string(markers.unescape(escaped)); got != collidingContent {
+		t.Errorf("Expected unescape to restore %q, got %q", collidingContent, got)
+	}
+
+	if _, changed := markers.escape([]byte("package main\n")); changed {
+		t.Errorf("Expected content without markers to be left alone")
+	}
+}
+
+// TestDelimiterCollisionModes checks that escape and auto produce output extract can parse
+func TestDelimiterCollisionModes(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"docs/format.md": collidingContent,
+		"main.go":        "package main\n",
+	})
+
+	for _, mode := range []string{"escape", "auto"} {
+		cfg := &Config{RepoPath: repoDir, DelimiterCollision: mode}
+		output := runAndReadOutput(t, cfg)
+
+		switch mode {
+		case "escape":
+			if !strings.Contains(output, "\n,# BEGIN FILE: example.go\n") {
+				t.Errorf("Expected the quoted marker to be escaped, got:\n%s", output)
+			}
+		case "auto":
+			if !regexp.MustCompile(`# BEGIN FILE [0-9a-f]+: main\.go\n`).MatchString(output) {
+				t.Errorf("Expected randomized markers, got:\n%s", output)
+			}
+		}
+
+		extracted, err := parseCombinedOutput([]byte(output))
+		if err != nil {
+			t.Fatalf("%s: failed to parse output: %v", mode, err)
+		}
+		restored := make(map[string]string)
+		for _, file := range extracted {
+			restored[file.path] = string(file.content)
+		}
+		if restored[filepath.FromSlash("docs/format.md")] != collidingContent {
+			t.Errorf("%s: expected the colliding file to round-trip, got %q", mode, restored[filepath.FromSlash("docs/format.md")])
+		}
+		if len(extracted) != 2 {
+			t.Errorf("%s: expected 2 files, got %d", mode, len(extracted))
+		}
+	}
+}
+
+// TestDelimiterCollisionFail checks that fail mode stops before any output is written
+func TestDelimiterCollisionFail(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{"docs/format.md": collidingContent})
+	outputFile := filepath.Join(createTempDir(t, "colligo_output"), "combined.txt")
+
+	err := run(context.Background(), getLogger(), &Config{RepoPath: repoDir, OutputFile: outputFile, DelimiterCollision: "fail"})
+	if err == nil || !strings.Contains(err.Error(), "format.md") {
+		t.Errorf("Expected a collision error naming the file, got %v", err)
+	}
+	if _, statErr := os.Stat(outputFile); !os.IsNotExist(statErr) {
+		t.Errorf("Expected no output file to be written")
+	}
+}
@@ -0,0 +1,114 @@
+// File: src/cmd/depgraph.go
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go/build"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// dependencyGraph holds the Go packages of a run and the imports between them
+type dependencyGraph struct {
+	packages []string
+	edges    map[string][]string
+}
+
+// buildDependencyGraph finds the Go packages holding the selected files and the
+// imports between them. Package paths come from the module path in the
+// repository's go.mod; imports of packages outside the run are left out.
+func buildDependencyGraph(logger *slog.Logger, repoPath string, entries []fileEntry) *dependencyGraph {
+	modulePath := readModulePath(filepath.Join(repoPath, "go.mod"))
+	dirs := make(map[string]bool)
+	for _, entry := range entries {
+		if strings.EqualFold(filepath.Ext(entry.RelativePath), ".go") {
+			dirs[filepath.Dir(entry.RelativePath)] = true
+		}
+	}
+
+	imports := make(map[string][]string)
+	for dir := range dirs {
+		pkg, err := build.Default.ImportDir(filepath.Join(repoPath, dir), 0)
+		if err != nil {
+			var noGo *build.NoGoError
+			if !errors.As(err, &noGo) {
+				logger.Debug("Skipping directory in dependency graph", "dir", dir, "error", err)
+			}
+			continue
+		}
+		imports[packagePath(modulePath, dir)] = pkg.Imports
+	}
+
+	graph := &dependencyGraph{edges: make(map[string][]string)}
+	for pkg, pkgImports := range imports {
+		graph.packages = append(graph.packages, pkg)
+		for _, imported := range pkgImports {
+			if _, ok := imports[imported]; ok && imported != pkg {
+				graph.edges[pkg] = append(graph.edges[pkg], imported)
+			}
+		}
+		slices.Sort(graph.edges[pkg])
+	}
+	slices.Sort(graph.packages)
+	return graph
+}
+
+// packagePath is the import path of the package in dir, relative to the repository
+func packagePath(modulePath string, dir string) string {
+	dir = filepath.ToSlash(dir)
+	switch {
+	case modulePath == "":
+		return dir
+	case dir == ".":
+		return modulePath
+	}
+	return path.Join(modulePath, dir)
+}
+
+// readModulePath returns the module path declared in a go.mod file, or "" if the
+// file is missing or declares none
+func readModulePath(goModPath string) string {
+	file, err := os.Open(goModPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module")
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		rest, _, _ = strings.Cut(rest, "//")
+		name := strings.TrimSpace(rest)
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			name = unquoted
+		}
+		return name
+	}
+	return ""
+}
+
+// dot renders the graph in Graphviz DOT format, with every package as a node and an
+// edge from each package to each package it imports
+func (g *dependencyGraph) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	for _, pkg := range g.packages {
+		fmt.Fprintf(&b, "  %s;\n", strconv.Quote(pkg))
+	}
+	for _, pkg := range g.packages {
+		for _, imported := range g.edges[pkg] {
+			fmt.Fprintf(&b, "  %s -> %s;\n", strconv.Quote(pkg), strconv.Quote(imported))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
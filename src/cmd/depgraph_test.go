@@ -0,0 +1,52 @@
+// File: src/cmd/depgraph_test.go
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEmitDependencyGraph checks the DOT edges between packages of the repository
+func TestEmitDependencyGraph(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"go.mod":      "module example.com/app\n\ngo 1.22\n",
+		"a/a.go":      "package a\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/app/b\"\n)\n\nfunc A() { fmt.Println(b.B()) }\n",
+		"b/b.go":      "package b\n\nfunc B() string { return \"b\" }\n",
+		"main.go":     "package main\n\nimport \"example.com/app/a\"\n\nfunc main() { a.A() }\n",
+		"docs/doc.md": "# Docs\n",
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, EmitDependencyGraph: true})
+
+	expected := "\n\n# DEPENDENCY GRAPH\n\n" +
+		"digraph dependencies {\n" +
+		"  \"example.com/app\";\n" +
+		"  \"example.com/app/a\";\n" +
+		"  \"example.com/app/b\";\n" +
+		"  \"example.com/app\" -> \"example.com/app/a\";\n" +
+		"  \"example.com/app/a\" -> \"example.com/app/b\";\n" +
+		"}\n"
+	if !strings.HasSuffix(output, expected) {
+		t.Errorf("Expected the output to end with:\n%s\nGot:\n%s", expected, output)
+	}
+	if strings.Contains(output, "-> \"fmt\"") {
+		t.Errorf("Expected imports from outside the repository to be left out")
+	}
+}
+
+// TestReadModulePath checks parsing of the module directive
+func TestReadModulePath(t *testing.T) {
+	cases := map[string]string{
+		"module example.com/app\n":                    "example.com/app",
+		"// comment\nmodule \"example.com/quoted\"\n": "example.com/quoted",
+		"module example.com/c // trailing\n":          "example.com/c",
+		"modules x\n":                                 "",
+	}
+	for content, expected := range cases {
+		repoDir := createFixtureRepo(t, map[string]string{"go.mod": content})
+		if got := readModulePath(filepath.Join(repoDir, "go.mod")); got != expected {
+			t.Errorf("readModulePath(%q) = %q, expected %q", content, got, expected)
+		}
+	}
+}
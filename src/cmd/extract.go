@@ -0,0 +1,223 @@
+// File: src/cmd/extract.go
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// extractedFile is one file recovered from combined txt output
+type extractedFile struct {
+	path    string
+	content []byte
+}
+
+// runExtract implements the extract subcommand, which restores the files in a
+// combined txt output, and returns its exit code
+func runExtract(args []string) int {
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	input := fs.String("input", "", "Combined txt output to extract files from")
+	dest := fs.String("dest", ".", "Directory to restore the files into")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *input == "" {
+		fmt.Fprintln(os.Stderr, "extract: -input is required")
+		fs.Usage()
+		return 2
+	}
+
+	data, err := os.ReadFile(*input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "extract: %v\n", err)
+		return 1
+	}
+	files, err := parseCombinedOutput(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "extract: %v\n", err)
+		return 1
+	}
+	if err := writeExtractedFiles(*dest, files); err != nil {
+		fmt.Fprintf(os.Stderr, "extract: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Extracted %d files to %s\n", len(files), *dest)
+	return 0
+}
+
+// parseCombinedOutput splits combined txt output back into files. A block's content
+// starts after the first blank line following its BEGIN FILE marker and runs up to
+// the matching END FILE marker. Blocks without an END FILE marker, such as read
+// errors, are skipped.
+func parseCombinedOutput(data []byte) ([]extractedFile, error) {
+	markers, err := detectMarkers(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []extractedFile
+	pos := 0
+	for {
+		begin := indexAtLineStart(data, pos, markers.begin)
+		if begin < 0 {
+			return files, nil
+		}
+		lineEnd := bytes.IndexByte(data[begin:], '\n')
+		if lineEnd < 0 {
+			return files, nil
+		}
+		lineEnd += begin
+		text := markers.uncommentText(strings.TrimSuffix(string(data[begin+len(markers.begin):lineEnd]), markers.suffix))
+		header, err := parseBeginHeader(text)
+		if err != nil {
+			return nil, err
+		}
+
+		blank := bytes.Index(data[lineEnd:], []byte("\n\n"))
+		if blank < 0 {
+			return files, nil
+		}
+		start := lineEnd + blank + 2
+
+		endMarker := "\n\n" + markers.end + markers.commentText(header.markerPath) + markers.suffix
+		end := bytes.Index(data[start:], []byte(endMarker+"\n"))
+		if end < 0 && bytes.HasSuffix(data[start:], []byte(endMarker)) {
+			end = len(data) - start - len(endMarker)
+		}
+		if end < 0 {
+			pos = start
+			continue
+		}
+
+		content := data[start : start+end]
+		if header.escaped {
+			content = markers.unescape(content)
+		}
+		// Later chunks of a split file continue the block before them
+		if last := len(files) - 1; header.chunk > 1 && last >= 0 && files[last].path == header.filePath {
+			files[last].content = slices.Concat(files[last].content, content)
+		} else {
+			files = append(files, extractedFile{path: header.filePath, content: content})
+		}
+		pos = start + end + len(endMarker)
+	}
+}
+
+// detectMarkers works out from the first BEGIN FILE marker which -header-comment-style
+// the output was written with, and whether it uses the default markers or a token
+// from -delimiter-collision auto
+func detectMarkers(data []byte) (fileMarkers, error) {
+	style, begin := "", -1
+	for _, candidate := range headerCommentStyles {
+		open, _ := commentDelimiters(candidate)
+		if i := indexAtLineStart(data, 0, open+"BEGIN FILE"); i >= 0 && (begin < 0 || i < begin) {
+			style, begin = candidate, i
+		}
+	}
+	if begin < 0 {
+		return markersFor("", ""), nil
+	}
+	open, _ := commentDelimiters(style)
+	rest := string(data[begin+len(open+"BEGIN FILE"):])
+	if strings.HasPrefix(rest, ": ") {
+		return markersFor(style, ""), nil
+	}
+	token, _, found := strings.Cut(strings.TrimPrefix(rest, " "), ": ")
+	if _, err := hex.DecodeString(token); !found || err != nil || token == "" {
+		return fileMarkers{}, errors.New("unrecognized BEGIN FILE marker")
+	}
+	return markersFor(style, token), nil
+}
+
+// Match the header fields written by -git-diff-stat, -chunk-tokens and
+// -emit-change-frequency
+var (
+	diffStatFieldPattern    = regexp.MustCompile(` insertions=\d+ deletions=\d+$`)
+	chunkFieldPattern       = regexp.MustCompile(` chunk=(\d+)/\d+$`)
+	changeCountFieldPattern = regexp.MustCompile(` change-count=\d+$`)
+)
+
+// beginHeader is what the BEGIN FILE marker says about a block
+type beginHeader struct {
+	// markerPath is the path used in the markers
+	markerPath string
+	filePath   string
+	escaped    bool
+	// chunk is the 1-based index of the block within its file when the file was
+	// split by -chunk-tokens, and 0 otherwise
+	chunk int
+}
+
+// parseBeginHeader parses the text after the BEGIN FILE marker. Fields are stripped
+// from the right: original-path always comes last, then escaped, then the chunk, the
+// change count and the -git-diff-stat counts. When the header carries an
+// original-path field the marker path is percent-encoded and is decoded to restore
+// the file.
+func parseBeginHeader(text string) (beginHeader, error) {
+	var header beginHeader
+	markerPath, original, encoded := strings.Cut(text, originalPathField)
+	markerPath, header.escaped = strings.CutSuffix(markerPath, escapedField)
+	if match := chunkFieldPattern.FindStringSubmatchIndex(markerPath); match != nil {
+		header.chunk, _ = strconv.Atoi(markerPath[match[2]:match[3]])
+		markerPath = markerPath[:match[0]]
+	}
+	markerPath = changeCountFieldPattern.ReplaceAllString(markerPath, "")
+	markerPath = diffStatFieldPattern.ReplaceAllString(markerPath, "")
+	header.markerPath, header.filePath = markerPath, markerPath
+	if !encoded {
+		return header, nil
+	}
+	decoded, err := url.PathUnescape(markerPath)
+	if err != nil {
+		return beginHeader{}, fmt.Errorf("invalid encoded path %q: %w", markerPath, err)
+	}
+	if decoded != filepath.ToSlash(original) {
+		return beginHeader{}, fmt.Errorf("encoded path %q does not match original-path %q", markerPath, original)
+	}
+	header.filePath = decoded
+	return header, nil
+}
+
+// indexAtLineStart finds the first occurrence of marker at the start of a line at or after pos
+func indexAtLineStart(data []byte, pos int, marker string) int {
+	for pos < len(data) {
+		i := bytes.Index(data[pos:], []byte(marker))
+		if i < 0 {
+			return -1
+		}
+		i += pos
+		if i == 0 || data[i-1] == '\n' {
+			return i
+		}
+		pos = i + 1
+	}
+	return -1
+}
+
+// writeExtractedFiles restores files under dest, refusing paths that would escape it
+func writeExtractedFiles(dest string, files []extractedFile) error {
+	for _, file := range files {
+		relativePath := filepath.FromSlash(file.path)
+		if !filepath.IsLocal(relativePath) {
+			return errors.New("refusing to extract path outside the destination: " + file.path)
+		}
+		target := filepath.Join(dest, relativePath)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, file.content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
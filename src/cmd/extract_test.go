@@ -0,0 +1,142 @@
+// File: src/cmd/extract_test.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestEncodePathsRoundTrip checks that paths with special characters are encoded in
+// the markers and decoded again by extract
+func TestEncodePathsRoundTrip(t *testing.T) {
+	files := map[string]string{
+		"my file (1).txt":     "hello\n",
+		"dir with space/ü.go": "package ü\n",
+		"plain.go":            "package plain\n",
+	}
+	repoDir := createFixtureRepo(t, files)
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, EncodePaths: true})
+
+	if !strings.Contains(output, "# BEGIN FILE: my%20file%20%281%29.txt original-path=my file (1).txt\n") {
+		t.Errorf("Expected an encoded BEGIN marker with the original path, got:\n%s", output)
+	}
+	if !strings.Contains(output, "# END FILE: my%20file%20%281%29.txt\n") {
+		t.Errorf("Expected an encoded END marker")
+	}
+	if !strings.Contains(output, "# BEGIN FILE: dir%20with%20space/%C3%BC.go ") {
+		t.Errorf("Expected directory separators to survive encoding")
+	}
+
+	extracted, err := parseCombinedOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	destDir := createTempDir(t, "colligo_extract")
+	if err := writeExtractedFiles(destDir, extracted); err != nil {
+		t.Fatalf("Failed to extract files: %v", err)
+	}
+	for name, content := range files {
+		restored, err := os.ReadFile(filepath.Join(destDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("Expected %s to be extracted: %v", name, err)
+			continue
+		}
+		if string(restored) != content {
+			t.Errorf("Expected %s to contain %q, got %q", name, content, restored)
+		}
+	}
+}
+
+// TestParseCombinedOutputPlain checks extraction from output without encoded paths
+func TestParseCombinedOutputPlain(t *testing.T) {
+	output := "\n\n# BEGIN FILE: a.go\n\npackage a\n\n\n# END FILE: a.go\n\n" +
+		"\n\n# BEGIN FILE: broken.go\n\n# Error reading broken.go: permission denied\n" +
+		"\n\n# BEGIN FILE: empty.txt\n\n\n\n# END FILE: empty.txt\n\n"
+
+	extracted, err := parseCombinedOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if len(extracted) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(extracted))
+	}
+	if extracted[0].path != "a.go" || string(extracted[0].content) != "package a\n" {
+		t.Errorf("Unexpected first file: %s %q", extracted[0].path, extracted[0].content)
+	}
+	if extracted[1].path != "empty.txt" || len(extracted[1].content) != 0 {
+		t.Errorf("Unexpected second file: %s %q", extracted[1].path, extracted[1].content)
+	}
+
+	if err := writeExtractedFiles(createTempDir(t, "colligo_extract"), []extractedFile{{path: "../escape.txt"}}); err == nil {
+		t.Errorf("Expected a path outside the destination to be refused")
+	}
+}
+
+// TestHeaderCommentStyles checks that extract detects the comment style of the
+// markers written by each -header-comment-style, that the other comment lines follow
+// the style, and that paths cannot break XML comments
+func TestHeaderCommentStyles(t *testing.T) {
+	files := map[string]string{
+		"a.go":            "package a\n",
+		"web/page.html":   "<p>hi</p>\n",
+		"docs/a--b%2D.md": "dashes\n",
+	}
+	repoDir := createFixtureRepo(t, files)
+
+	cases := []struct {
+		style   string
+		begin   string
+		end     string
+		dashes  string
+		comment string
+	}{
+		{"hash", "# BEGIN FILE: a.go\n", "# END FILE: a.go\n", "# BEGIN FILE: docs/a--b%2D.md\n", "# %s\n"},
+		{"double-slash", "// BEGIN FILE: a.go\n", "// END FILE: a.go\n", "// BEGIN FILE: docs/a--b%2D.md\n", "// %s\n"},
+		{"xml", "<!-- BEGIN FILE: a.go -->\n", "<!-- END FILE: a.go -->\n", "<!-- BEGIN FILE: docs/a-%2Db%252D.md -->\n", "<!-- %s -->\n"},
+		{"html", "<!-- BEGIN FILE: a.go -->\n", "<!-- END FILE: a.go -->\n", "<!-- BEGIN FILE: docs/a-%2Db%252D.md -->\n", "<!-- %s -->\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.style, func(t *testing.T) {
+			output := runAndReadOutput(t, &Config{RepoPath: repoDir, HeaderCommentStyle: c.style, Metadata: true, Summary: true, OutputChecksum: true})
+			if !strings.Contains(output, c.begin) || !strings.Contains(output, c.end) || !strings.Contains(output, c.dashes) {
+				t.Fatalf("Expected markers %q, %q and %q, got:\n%s", c.begin, c.end, c.dashes, output)
+			}
+			for _, line := range []string{"size: 10", "language: go", "SUMMARY", "Files: 3"} {
+				if !strings.Contains(output, fmt.Sprintf(c.comment, line)) {
+					t.Errorf("Expected the comment %q, got:\n%s", fmt.Sprintf(c.comment, line), output)
+				}
+			}
+			open, close, _ := strings.Cut(c.comment, "%s")
+			if !regexp.MustCompile(regexp.QuoteMeta(open+"OUTPUT-SHA256: ") + `[0-9a-f]{64}` + regexp.QuoteMeta(close) + `$`).MatchString(output) {
+				t.Errorf("Expected the output to end with a checksum trailer in the %s style, got:\n%s", c.style, output)
+			}
+			if (c.style == "xml" || c.style == "html") && strings.Count(output, "--") != 2*strings.Count(output, "<!--") {
+				t.Errorf("Expected no -- inside the comments, got:\n%s", output)
+			}
+
+			extracted, err := parseCombinedOutput([]byte(output))
+			if err != nil {
+				t.Fatalf("Failed to parse output: %v", err)
+			}
+			if len(extracted) != len(files) {
+				t.Fatalf("Expected %d files, got %d", len(files), len(extracted))
+			}
+			for _, file := range extracted {
+				if string(file.content) != files[file.path] {
+					t.Errorf("Expected %s to contain %q, got %q", file.path, files[file.path], file.content)
+				}
+			}
+		})
+	}
+
+	cfg := &Config{RepoPath: repoDir, HeaderCommentStyle: "semicolon"}
+	if err := run(context.Background(), getLogger(), cfg); err == nil {
+		t.Errorf("Expected an unknown header comment style to be rejected")
+	}
+}
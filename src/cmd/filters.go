@@ -0,0 +1,369 @@
+// File: src/cmd/filters.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// stringListFlag collects the values of a repeatable string flag into list. The first
+// value on the command line replaces what list held, such as the values from a config
+// file, and later ones are appended.
+type stringListFlag struct {
+	list *[]string
+	set  bool
+}
+
+func (s *stringListFlag) String() string {
+	if s.list == nil {
+		return ""
+	}
+	return strings.Join(*s.list, ",")
+}
+
+func (s *stringListFlag) Set(value string) error {
+	if !s.set {
+		*s.list = nil
+		s.set = true
+	}
+	*s.list = append(*s.list, value)
+	return nil
+}
+
+// inclusionFilter decides which walked entries end up in the output. Exclusion
+// rules run first; the force-include list is evaluated last and overrides them.
+// The always-include list only exempts files from the hidden-file filter. Content
+// restrictions are not exclusion rules: markRestricted applies them to the entries
+// this filter keeps, force-included ones included, so they win over force-include.
+type inclusionFilter struct {
+	excludePatterns    []string
+	compiledExtensions map[string]bool
+	forceInclude       map[string]bool
+	// alwaysInclude holds base names of project files the hidden-file filter never
+	// excludes; explicit excludes and the paths file still apply to them
+	alwaysInclude map[string]bool
+	// Excluded directories the walk still enters to reach force-included files
+	excludedDirs []string
+	// allowedPaths, when set, are the only directory prefixes the walk includes
+	allowedPaths []string
+	// maxFileDepth, when set, excludes files below that many directories
+	maxFileDepth *int
+	// docsOnly excludes every file that is not documentation
+	docsOnly bool
+	// maxDepth, when set, is the deepest directory level the walk enters, unless
+	// depthMap has a limit for the top-level directory
+	maxDepth *int
+	depthMap map[string]int
+	// skipDotExtensions holds the lowercased extensions of hidden files to exclude
+	skipDotExtensions map[string]bool
+}
+
+// newInclusionFilter prepares the filter for the rules in cfg
+func newInclusionFilter(cfg *Config) (*inclusionFilter, error) {
+	filter := &inclusionFilter{
+		excludePatterns:   cfg.ExcludePatterns,
+		forceInclude:      make(map[string]bool),
+		alwaysInclude:     make(map[string]bool),
+		maxFileDepth:      cfg.MaxFileDepth,
+		docsOnly:          cfg.DocsOnly,
+		maxDepth:          cfg.MaxDepth,
+		depthMap:          cfg.DepthMap,
+		skipDotExtensions: make(map[string]bool),
+	}
+	for _, ext := range cfg.SkipDotFilesWithExtensions {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		filter.skipDotExtensions[strings.ToLower(ext)] = true
+	}
+	for _, name := range cfg.AlwaysInclude {
+		filter.alwaysInclude[name] = true
+	}
+	if cfg.PathsFile != "" {
+		allowed, err := readPathsFile(cfg.PathsFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(allowed) == 0 {
+			return nil, fmt.Errorf("paths file %s lists no paths", cfg.PathsFile)
+		}
+		filter.allowedPaths = allowed
+	}
+	for _, forced := range cfg.ForceInclude {
+		filter.forceInclude[slashPath(forced)] = true
+	}
+	if cfg.ExcludeCompiled {
+		filter.compiledExtensions = make(map[string]bool)
+		for _, ext := range defaultCompiledExtensions {
+			filter.compiledExtensions[ext] = true
+		}
+		for _, ext := range cfg.CompiledExtensions {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			filter.compiledExtensions[strings.ToLower(ext)] = true
+		}
+	}
+	return filter, nil
+}
+
+// readPathsFile reads the directory prefixes for -paths-file, one per line relative to
+// the repository. Blank lines and lines starting with # are ignored.
+func readPathsFile(name string) ([]string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var allowed []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		allowed = append(allowed, strings.TrimSuffix(slashPath(line), "/"))
+	}
+	return allowed, nil
+}
+
+// Extensions of compiled artifacts pruned by -exclude-compiled
+var defaultCompiledExtensions = []string{
+	".o", ".a", ".obj", ".lib", ".so", ".dylib", ".dll", ".exe",
+	".pyc", ".pyo", ".class", ".jar", ".wasm", ".test",
+}
+
+// Hidden files that commonly hold credentials, excluded whatever their extension.
+// Every .env file is excluded too, including variants like .env.local.
+var credentialDotFiles = map[string]bool{
+	".npmrc":           true,
+	".netrc":           true,
+	".pypirc":          true,
+	".pgpass":          true,
+	".git-credentials": true,
+	".htpasswd":        true,
+	".dockercfg":       true,
+}
+
+// isCredentialDotFile reports whether a hidden file's name marks it as holding secrets
+func isCredentialDotFile(name string) bool {
+	name = strings.ToLower(name)
+	return credentialDotFiles[name] || name == ".env" || strings.HasPrefix(name, ".env.")
+}
+
+// Directories that only ever hold compiled artifacts, pruned by -exclude-compiled
+var compiledDirectories = map[string]bool{
+	"__pycache__": true,
+}
+
+// excludeReason returns why an entry is excluded, or an empty string if it is included
+func (f *inclusionFilter) excludeReason(relativePath string, d fs.DirEntry) string {
+	rel := slashPath(relativePath)
+
+	for _, dir := range f.excludedDirs {
+		if strings.HasPrefix(rel, dir+"/") {
+			return "inside excluded directory " + dir
+		}
+	}
+	if f.allowedPaths != nil && !f.allowed(rel, d.IsDir()) {
+		return "outside the paths file"
+	}
+
+	// Deep directories are still walked; only the files in them are dropped
+	if f.maxFileDepth != nil && !d.IsDir() && strings.Count(rel, "/") > *f.maxFileDepth {
+		return "deeper than max file depth"
+	}
+
+	if d.IsDir() && rel != "." {
+		if limit, ok := f.depthLimit(rel); ok && strings.Count(rel, "/")+1 > limit {
+			return fmt.Sprintf("deeper than max depth %d", limit)
+		}
+	}
+	if f.docsOnly && !d.IsDir() && !isDocumentation(rel) {
+		return "not documentation"
+	}
+
+	// Prune hidden directories except .github; hidden files are only excluded when
+	// they hold credentials or their extension is listed, so files like
+	// .eslintrc.json still make it in
+	if isHidden(d.Name()) {
+		if d.IsDir() && d.Name() != ".github" {
+			return "hidden"
+		}
+		if !d.IsDir() && !f.alwaysInclude[d.Name()] {
+			if isCredentialDotFile(d.Name()) {
+				return "hidden credentials file"
+			}
+			if f.skipDotExtensions[strings.ToLower(path.Ext(d.Name()))] {
+				return "hidden file with skipped extension"
+			}
+		}
+	}
+	if f.compiledExtensions != nil {
+		if d.IsDir() && compiledDirectories[d.Name()] {
+			return "compiled artifacts"
+		}
+		if !d.IsDir() && f.compiledExtensions[strings.ToLower(path.Ext(rel))] {
+			return "compiled artifact"
+		}
+	}
+	for _, pattern := range f.excludePatterns {
+		if matchesPattern(pattern, rel) {
+			return "exclude pattern " + pattern
+		}
+	}
+	return ""
+}
+
+// allowed reports whether a path lies inside one of the allowed prefixes. Directories
+// above an allowed prefix are allowed too, so the walk can reach it.
+func (f *inclusionFilter) allowed(rel string, isDir bool) bool {
+	if rel == "." {
+		return true
+	}
+	for _, prefix := range f.allowedPaths {
+		if prefix == "." || rel == prefix || strings.HasPrefix(rel, prefix+"/") {
+			return true
+		}
+		if isDir && strings.HasPrefix(prefix, rel+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// depthLimit returns the deepest directory level allowed under the top-level directory
+// of rel: its -depth-map entry if it has one, otherwise -max-depth
+func (f *inclusionFilter) depthLimit(rel string) (int, bool) {
+	top, _, _ := strings.Cut(rel, "/")
+	if limit, ok := f.depthMap[top]; ok {
+		return limit, true
+	}
+	if f.maxDepth != nil {
+		return *f.maxDepth, true
+	}
+	return 0, false
+}
+
+// parseDepthMap parses the value of -depth-map, such as "docs=1,src=20"
+func parseDepthMap(value string) (map[string]int, error) {
+	depths := make(map[string]int)
+	for _, item := range parseCommaList(value) {
+		dir, depth, found := strings.Cut(item, "=")
+		limit, err := strconv.Atoi(strings.TrimSpace(depth))
+		dir = strings.Trim(slashPath(strings.TrimSpace(dir)), "/")
+		if !found || err != nil || limit < 0 || dir == "" || dir == "." || strings.Contains(dir, "/") {
+			return nil, fmt.Errorf("invalid depth map entry %q: expected top-level-dir=N", item)
+		}
+		depths[dir] = limit
+	}
+	return depths, nil
+}
+
+// forced reports whether a file is on the force-include list
+func (f *inclusionFilter) forced(relativePath string) bool {
+	return f.forceInclude[slashPath(relativePath)]
+}
+
+// isDocumentation reports whether a file has one of the documentation extensions
+func isDocumentation(rel string) bool {
+	return docExtensions[strings.ToLower(path.Ext(rel))]
+}
+
+// descendExcluded reports whether the walk must still enter an excluded directory
+// because a force-included file lives under it. Everything else under the directory
+// stays excluded.
+func (f *inclusionFilter) descendExcluded(relativePath string) bool {
+	rel := slashPath(relativePath)
+	for forced := range f.forceInclude {
+		if strings.HasPrefix(forced, rel+"/") {
+			f.excludedDirs = append(f.excludedDirs, rel)
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern reports whether a glob pattern matches the slash-separated relative
+// path or its base name, so "*.log" matches at any depth while "docs/*.md" is anchored
+func matchesPattern(pattern string, rel string) bool {
+	pattern = filepath.ToSlash(pattern)
+	if matched, _ := path.Match(pattern, rel); matched {
+		return true
+	}
+	matched, _ := path.Match(pattern, path.Base(rel))
+	return matched
+}
+
+// slashPath normalizes a relative path for comparisons across platforms
+func slashPath(relativePath string) string {
+	return filepath.ToSlash(filepath.Clean(relativePath))
+}
+
+// restriction holds the strings of -exclude-if-contains, lowercased when matching
+// ignores case
+type restriction struct {
+	needles    [][]byte
+	ignoreCase bool
+}
+
+func newRestriction(restricted []string, ignoreCase bool) *restriction {
+	r := &restriction{ignoreCase: ignoreCase}
+	for _, s := range restricted {
+		needle := []byte(s)
+		if ignoreCase {
+			needle = bytes.ToLower(needle)
+		}
+		r.needles = append(r.needles, needle)
+	}
+	return r
+}
+
+// matches reports whether content contains one of the restricted strings
+func (r *restriction) matches(content []byte) bool {
+	if r.ignoreCase {
+		content = bytes.ToLower(content)
+	}
+	for _, needle := range r.needles {
+		if bytes.Contains(content, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictedNote is the placeholder written for a file -exclude-if-contains excludes
+func restrictedNote(relativePath string) string {
+	return "# FILE EXCLUDED (contains restricted string): " + filepath.ToSlash(relativePath)
+}
+
+// markRestricted replaces the content of every file containing one of the restricted
+// strings with a placeholder. It reads the files as they are on disk, before any
+// transform, and applies to force-included files too (see inclusionFilter). A file that cannot be checked
+// is excluded as well. The restriction stays on every entry so the content is
+// checked again as it is written, in case the file changed in between.
+func markRestricted(logger *slog.Logger, entries []fileEntry, restricted []string, ignoreCase bool) {
+	r := newRestriction(restricted, ignoreCase)
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Note != "" {
+			continue
+		}
+		entry.Restricted = r
+		content, err := os.ReadFile(entry.Path)
+		if err != nil {
+			logger.Warn("Excluding file that could not be checked for restricted strings", "file", entry.RelativePath, "error", err)
+			entry.Note = restrictedNote(entry.RelativePath)
+			continue
+		}
+		if r.matches(content) {
+			logger.Info("Excluding file containing a restricted string", "file", entry.RelativePath)
+			entry.Note = restrictedNote(entry.RelativePath)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+// File: src/cmd/filters_test.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMatchesPattern checks glob matching against paths and base names
+func TestMatchesPattern(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		rel      string
+		expected bool
+	}{
+		{"*.log", "debug.log", true},
+		{"*.log", "logs/debug.log", true},
+		{"docs/*.md", "docs/guide.md", true},
+		{"docs/*.md", "other/docs/guide.md", false},
+		{"go.sum", "go.sum", true},
+		{"*.log", "main.go", false},
+	}
+
+	for _, c := range cases {
+		if got := matchesPattern(c.pattern, c.rel); got != c.expected {
+			t.Errorf("matchesPattern(%q, %q) = %v, expected %v", c.pattern, c.rel, got, c.expected)
+		}
+	}
+}
+
+// TestForceIncludeOverridesExclusions checks that force-included files survive exclude patterns and hidden rules
+func TestForceIncludeOverridesExclusions(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"go.mod":            "module example\n",
+		"go.sum":            "checksum\n",
+		"main.go":           "package main\n",
+		".config/keep.yaml": "keep: true\n",
+		".config/drop.yaml": "drop: true\n",
+	})
+
+	output := runAndReadOutput(t, &Config{
+		RepoPath:        repoDir,
+		ExcludePatterns: []string{"go.*"},
+		ForceInclude:    []string{"go.mod", ".config/keep.yaml"},
+	})
+
+	for _, included := range []string{"go.mod", "main.go", ".config/keep.yaml"} {
+		if !strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(included)+"\n") {
+			t.Errorf("Expected %s to be included", included)
+		}
+	}
+	for _, excluded := range []string{"go.sum", ".config/drop.yaml"} {
+		if strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(excluded)+"\n") {
+			t.Errorf("Expected %s to be excluded", excluded)
+		}
+	}
+}
+
+// TestExcludeCompiled checks that compiled artifacts are pruned and the list can be extended
+func TestExcludeCompiled(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"main.go":                   "package main\n",
+		"lib.o":                     "\x7fELF",
+		"Main.class":                "\xca\xfe\xba\xbe",
+		"pkg/__pycache__/mod.py":    "cached\n",
+		"pkg/mod.py":                "print(1)\n",
+		"build/artifact.bin":        "binary",
+		"build/artifact.BIN.backup": "not compiled",
+	})
+
+	output := runAndReadOutput(t, &Config{
+		RepoPath:           repoDir,
+		ExcludeCompiled:    true,
+		CompiledExtensions: []string{"bin"},
+	})
+
+	for _, included := range []string{"main.go", "pkg/mod.py", "build/artifact.BIN.backup"} {
+		if !strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(included)+"\n") {
+			t.Errorf("Expected %s to be included", included)
+		}
+	}
+	for _, excluded := range []string{"lib.o", "Main.class", "pkg/__pycache__/mod.py", "build/artifact.bin"} {
+		if strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(excluded)+"\n") {
+			t.Errorf("Expected %s to be excluded", excluded)
+		}
+	}
+}
+
+// TestPathsFile checks that only the listed directory prefixes are walked
+func TestPathsFile(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"main.go":          "package main\n",
+		"src/api/api.go":   "package api\n",
+		"src/api/v2/v2.go": "package v2\n",
+		"src/web/web.go":   "package web\n",
+		"docs/guide.md":    "# Guide\n",
+		"tools/gen.go":     "package tools\n",
+	})
+	pathsFile := filepath.Join(createTempDir(t, "colligo_paths"), "paths.txt")
+	if err := os.WriteFile(pathsFile, []byte("# focus areas\nsrc/api/\n\ndocs\n"), 0644); err != nil {
+		t.Fatalf("Failed to write paths file: %v", err)
+	}
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, PathsFile: pathsFile})
+
+	for _, included := range []string{"src/api/api.go", "src/api/v2/v2.go", "docs/guide.md"} {
+		if !strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(included)+"\n") {
+			t.Errorf("Expected %s to be included", included)
+		}
+	}
+	for _, excluded := range []string{"main.go", "src/web/web.go", "tools/gen.go"} {
+		if strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(excluded)+"\n") {
+			t.Errorf("Expected %s to be excluded", excluded)
+		}
+	}
+}
+
+// TestMaxFileDepth checks that deep files are dropped while their directories are still walked
+func TestMaxFileDepth(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"main.go":           "package main\n",
+		"pkg/lib.go":        "package pkg\n",
+		"pkg/inner/deep.go": "package inner\n",
+	})
+	depth := 1
+	cfg := &Config{
+		RepoPath:     repoDir,
+		OutputFile:   filepath.Join(createTempDir(t, "colligo_output"), "combined.txt"),
+		MaxFileDepth: &depth,
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if err := run(context.Background(), logger, cfg); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	data, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	output := string(data)
+
+	for _, included := range []string{"main.go", "pkg/lib.go"} {
+		if !strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(included)+"\n") {
+			t.Errorf("Expected %s to be included", included)
+		}
+	}
+	if strings.Contains(output, "deep.go") {
+		t.Errorf("Expected pkg/inner/deep.go to be excluded")
+	}
+	// The file is only seen, and skipped, if the walk entered pkg/inner
+	if !strings.Contains(logs.String(), "reason=\"deeper than max file depth\"") {
+		t.Errorf("Expected the walker to visit and skip the deep file, got logs:\n%s", logs.String())
+	}
+}
+
+// TestAlwaysInclude checks that project files survive the hidden-file filter by base
+// name, while explicit excludes and the paths file still win
+func TestAlwaysInclude(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		".gitignore":     "build/\n",
+		".env":           "SECRET=1\n",
+		"go.mod":         "module example\n",
+		"main.go":        "package main\n",
+		"sub/README.md":  "# Sub\n",
+		"sub/notes.md":   "notes\n",
+		".cache/go.mod":  "module cached\n",
+		"web/.gitignore": "node_modules/\n",
+		".tool.log":      "kept\n",
+		".other.log":     "skipped\n",
+	})
+
+	output := runAndReadOutput(t, &Config{
+		RepoPath:                   repoDir,
+		ExcludePatterns:            []string{"go.mod", "*.md"},
+		AlwaysInclude:              append(defaultConfig().AlwaysInclude, ".tool.log"),
+		SkipDotFilesWithExtensions: defaultConfig().SkipDotFilesWithExtensions,
+	})
+
+	for _, included := range []string{".gitignore", ".tool.log", "main.go", "web/.gitignore"} {
+		if !strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(included)+"\n") {
+			t.Errorf("Expected %s to be included", included)
+		}
+	}
+	// Explicit excludes win over the always-include list, and hidden directories are
+	// still pruned, so files inside them are never reached
+	for _, excluded := range []string{".env", "go.mod", "sub/README.md", "sub/notes.md", ".cache/go.mod", ".other.log"} {
+		if strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(excluded)+"\n") {
+			t.Errorf("Expected %s to be excluded", excluded)
+		}
+	}
+
+	// The paths file wins too
+	pathsFile := filepath.Join(createTempDir(t, "colligo_paths"), "paths.txt")
+	if err := os.WriteFile(pathsFile, []byte("sub\n"), 0644); err != nil {
+		t.Fatalf("Failed to write paths file: %v", err)
+	}
+	output = runAndReadOutput(t, &Config{RepoPath: repoDir, PathsFile: pathsFile, AlwaysInclude: defaultConfig().AlwaysInclude})
+	for _, excluded := range []string{".gitignore", "go.mod", "web/.gitignore"} {
+		if strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(excluded)+"\n") {
+			t.Errorf("Expected %s outside the paths file to be excluded", excluded)
+		}
+	}
+	if !strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash("sub/README.md")+"\n") {
+		t.Errorf("Expected sub/README.md to be included")
+	}
+}
+
+// TestDocsOnly checks that only documentation survives, including from the always-include list
+func TestDocsOnly(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"README.md":        "# Project\n",
+		"go.mod":           "module example\n",
+		"main.go":          "package main\n",
+		"docs/guide.rst":   "Guide\n",
+		"docs/manual.adoc": "= Manual\n",
+		"docs/NOTES.TXT":   "notes\n",
+		"docs/diagram.png": "png",
+	})
+
+	output := runAndReadOutput(t, &Config{
+		RepoPath:      repoDir,
+		DocsOnly:      true,
+		AlwaysInclude: defaultConfig().AlwaysInclude,
+	})
+
+	for _, included := range []string{"README.md", "docs/guide.rst", "docs/manual.adoc", "docs/NOTES.TXT"} {
+		if !strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(included)+"\n") {
+			t.Errorf("Expected %s to be included", included)
+		}
+	}
+	for _, excluded := range []string{"go.mod", "main.go", "docs/diagram.png"} {
+		if strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(excluded)+"\n") {
+			t.Errorf("Expected %s to be excluded", excluded)
+		}
+	}
+}
+
+// TestDepthMap checks per top-level directory depth limits with a global fallback
+func TestDepthMap(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"top.go":          "package top\n",
+		"docs/intro.md":   "# Intro\n",
+		"docs/api/ref.md": "# Ref\n",
+		"src/a/b/c/d.go":  "package c\n",
+		"lib/x/y.go":      "package x\n",
+		"lib/z.go":        "package lib\n",
+	})
+	depths, err := parseDepthMap("docs=1, src=20")
+	if err != nil {
+		t.Fatalf("parseDepthMap failed: %v", err)
+	}
+	maxDepth := 1
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, MaxDepth: &maxDepth, DepthMap: depths})
+
+	for _, included := range []string{"top.go", "docs/intro.md", "src/a/b/c/d.go", "lib/z.go"} {
+		if !strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(included)+"\n") {
+			t.Errorf("Expected %s to be included", included)
+		}
+	}
+	for _, excluded := range []string{"docs/api/ref.md", "lib/x/y.go"} {
+		if strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(excluded)+"\n") {
+			t.Errorf("Expected %s to be excluded", excluded)
+		}
+	}
+
+	for _, invalid := range []string{"docs", "docs=-1", "docs=x", "src/app=2", "=3"} {
+		if _, err := parseDepthMap(invalid); err == nil {
+			t.Errorf("Expected parseDepthMap(%q) to fail", invalid)
+		}
+	}
+}
+
+// TestSkipDotFilesWithExtensions checks that only hidden files with a listed extension are skipped
+func TestSkipDotFilesWithExtensions(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		".eslintrc.json":             "{}\n",
+		".prettierrc":                "{}\n",
+		".main.swp":                  "swap\n",
+		".build.LOG":                 "log\n",
+		".github/workflows/main.yml": "on: push\n",
+		".cache/entry.json":          "{}\n",
+		"main.go":                    "package main\n",
+	})
+
+	output := runAndReadOutput(t, &Config{
+		RepoPath:                   repoDir,
+		SkipDotFilesWithExtensions: defaultConfig().SkipDotFilesWithExtensions,
+	})
+
+	for _, included := range []string{".eslintrc.json", ".prettierrc", ".github/workflows/main.yml", "main.go"} {
+		if !strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(included)+"\n") {
+			t.Errorf("Expected %s to be included", included)
+		}
+	}
+	// Extensions match case-insensitively, and hidden directories are still pruned
+	for _, excluded := range []string{".main.swp", ".build.LOG", ".cache/entry.json"} {
+		if strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash(excluded)+"\n") {
+			t.Errorf("Expected %s to be excluded", excluded)
+		}
+	}
+}
+
+// TestSkipCredentialDotFiles checks that hidden files holding secrets stay excluded
+// whatever their extension, and that listed extensions need no leading dot
+func TestSkipCredentialDotFiles(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		".env":            "SECRET=1\n",
+		".env.local":      "SECRET=2\n",
+		".env.production": "SECRET=3\n",
+		".npmrc":          "//registry.npmjs.org/:_authToken=abc\n",
+		".netrc":          "machine example.com password hunter2\n",
+		".main.swp":       "swap\n",
+		".environment.md": "notes\n",
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, SkipDotFilesWithExtensions: []string{"swp"}})
+
+	for _, excluded := range []string{".env", ".env.local", ".env.production", ".npmrc", ".netrc", ".main.swp"} {
+		if strings.Contains(output, "# BEGIN FILE: "+excluded+"\n") {
+			t.Errorf("Expected %s to be excluded", excluded)
+		}
+	}
+	if !strings.Contains(output, "# BEGIN FILE: .environment.md\n") {
+		t.Errorf("Expected .environment.md to be included")
+	}
+}
+
+// TestExcludeIfContainsString checks that files with a restricted string become placeholders
+func TestExcludeIfContainsString(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"secret.txt": "Status: CONFIDENTIAL\n",
+		"lower.txt":  "status: confidential\n",
+		"public.txt": "Nothing to hide\n",
+		"license.go": "// key: ABC-123\npackage main\n",
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, ExcludeIfContains: []string{"CONFIDENTIAL", "ABC-123"}})
+
+	for _, excluded := range []string{"secret.txt", "license.go"} {
+		if !strings.Contains(output, "# BEGIN FILE: "+excluded+"\n\n# FILE EXCLUDED (contains restricted string): "+excluded+"\n") {
+			t.Errorf("Expected a placeholder for %s, got:\n%s", excluded, output)
+		}
+	}
+	for _, content := range []string{"Status: CONFIDENTIAL", "ABC-123\npackage"} {
+		if strings.Contains(output, content) {
+			t.Errorf("Expected %q to be kept out of the output", content)
+		}
+	}
+	for _, included := range []string{"status: confidential\n", "Nothing to hide\n"} {
+		if !strings.Contains(output, included) {
+			t.Errorf("Expected %q to be written with a case-sensitive match", included)
+		}
+	}
+
+	output = runAndReadOutput(t, &Config{RepoPath: repoDir, ExcludeIfContains: []string{"CONFIDENTIAL"}, IgnoreCase: true})
+	if strings.Contains(output, "status: confidential\n") || !strings.Contains(output, "Nothing to hide\n") {
+		t.Errorf("Expected -ignore-case to exclude lower.txt only, got:\n%s", output)
+	}
+}
+
+// TestExcludeIfContainsFailsClosed checks that a file that cannot be checked is
+// excluded, and that content changed after the check is checked again when written
+func TestExcludeIfContainsFailsClosed(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{"notes.txt": "public\n"})
+	path := filepath.Join(repoDir, "notes.txt")
+	entries := []fileEntry{
+		{Path: filepath.Join(repoDir, "missing.txt"), RelativePath: "missing.txt"},
+		{Path: path, RelativePath: "notes.txt"},
+	}
+	markRestricted(getLogger(), entries, []string{"SECRET"}, false)
+	if entries[0].Note != restrictedNote("missing.txt") {
+		t.Errorf("Expected an unreadable file to be excluded, got note %q", entries[0].Note)
+	}
+	if entries[1].Note != "" {
+		t.Fatalf("Expected notes.txt to pass the check, got note %q", entries[1].Note)
+	}
+
+	// The file gains a restricted string between the check and the write
+	if err := os.WriteFile(path, []byte("public\nSECRET\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify fixture file: %v", err)
+	}
+	var out bytes.Buffer
+	writer := bufio.NewWriter(&out)
+	sinks := []*outputSink{{path: "out.txt", writer: writer, format: &txtFormatter{}}}
+	if _, err := writeFileContent(getLogger(), sinks, &fileReader{}, entries[1]); err != nil {
+		t.Fatalf("writeFileContent failed: %v", err)
+	}
+	writer.Flush()
+	if strings.Contains(out.String(), "SECRET") || !strings.Contains(out.String(), restrictedNote("notes.txt")) {
+		t.Errorf("Expected the changed file to be replaced by its placeholder, got:\n%s", out.String())
+	}
+}
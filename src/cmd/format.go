@@ -0,0 +1,357 @@
+// File: src/cmd/format.go
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// collisionChecker is implemented by formats whose file markers can be confused with
+// file content
+type collisionChecker interface {
+	// collides reports whether content would make the output ambiguous
+	collides(content []byte) bool
+}
+
+// formatter renders the combined output in one output format
+type formatter interface {
+	// begin writes anything that precedes the first file
+	begin(w *bufio.Writer) error
+	// writeFile writes one file and its (transformed) content
+	writeFile(w *bufio.Writer, entry fileEntry, content []byte) error
+	// writeError records a file that could not be read
+	writeError(w *bufio.Writer, entry fileEntry, readErr error) error
+	// end writes anything that follows the last file, including the summary if
+	// one was requested
+	end(w *bufio.Writer, summary *runStats) error
+}
+
+// File extension used for the default output name of each format
+var formatExtensions = map[string]string{
+	"txt":      ".txt",
+	"org":      ".org",
+	"markdown": ".md",
+	"json":     ".json",
+	"ipynb":    ".ipynb",
+	"html":     ".html",
+	"latex":    ".tex",
+	// Distinct from .json so -formats can write both side by side
+	"slack-attachment": ".slack.json",
+	// Distinct from .txt for the same reason
+	"compact": ".compact.txt",
+}
+
+// newFormatter returns the formatter for the named output format, using what the run
+// has worked out so far in state
+func newFormatter(name string, cfg *Config, state *runState) (formatter, error) {
+	switch name {
+	case "", "txt":
+		return &txtFormatter{
+			separator:      cfg.BlockSeparator,
+			encodePaths:    cfg.EncodePaths,
+			markers:        markersFor(cfg.HeaderCommentStyle, state.delimiterToken),
+			escape:         state.delimiterCollision == "escape",
+			metadata:       cfg.Metadata,
+			frontMatter:    cfg.RAGMode,
+			stats:          state.repoStats,
+			graph:          state.dependencyGraph,
+			chunkTokens:    cfg.ChunkTokens,
+			respectSymbols: cfg.ChunkRespectSymbols,
+		}, nil
+	case "org":
+		return orgFormatter{title: filepath.Base(cfg.RepoPath), date: time.Now()}, nil
+	case "markdown":
+		return markdownFormatter{title: filepath.Base(cfg.RepoPath)}, nil
+	case "json":
+		return &jsonFormatter{repository: filepath.Base(cfg.RepoPath), lineOffsets: cfg.JSONLineOffsets}, nil
+	case "ipynb":
+		return &ipynbFormatter{repository: filepath.Base(cfg.RepoPath)}, nil
+	case "html":
+		return htmlFormatter{title: filepath.Base(cfg.RepoPath), inlineImages: cfg.InlineImages}, nil
+	case "latex":
+		return &latexFormatter{title: filepath.Base(cfg.RepoPath)}, nil
+	case "compact":
+		return compactFormatter{}, nil
+	case "slack-attachment":
+		return &slackFormatter{repository: filepath.Base(cfg.RepoPath), repoPath: cfg.RepoPath}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q (supported: %s)", name, strings.Join(formatNames(), ", "))
+}
+
+// formatNames lists the supported output formats
+func formatNames() []string {
+	var names []string
+	for name := range formatExtensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// txtFormatter writes the plain text format with BEGIN/END FILE markers
+type txtFormatter struct {
+	// separator replaces the blank-line padding around each block when set
+	separator *string
+	// encodePaths percent-encodes the marker paths and adds an original-path field
+	encodePaths bool
+	// markers defaults to markersFor("", "") when unset
+	markers fileMarkers
+	// escape comma-escapes content lines that look like markers
+	escape bool
+	// metadata adds comment lines describing the file after the BEGIN FILE marker
+	metadata bool
+	// frontMatter adds a front matter block for retrieval pipelines instead
+	frontMatter bool
+	// stats is written as a header before the first file when set
+	stats *repoStats
+	// graph is written after the last file when set
+	graph *dependencyGraph
+	// chunkTokens splits larger files into several blocks, at declarations when
+	// respectSymbols is set
+	chunkTokens    int
+	respectSymbols bool
+	blocks         int
+}
+
+func (f *txtFormatter) begin(w *bufio.Writer) error {
+	if f.stats == nil {
+		return nil
+	}
+	_, err := w.WriteString(f.stats.header(f.activeMarkers()))
+	return err
+}
+
+func (f *txtFormatter) end(w *bufio.Writer, summary *runStats) error {
+	markers := f.activeMarkers()
+	if f.graph != nil {
+		if _, err := w.WriteString("\n\n" + markers.comment("DEPENDENCY GRAPH") + "\n" + f.graph.dot()); err != nil {
+			return err
+		}
+	}
+	if summary == nil {
+		return nil
+	}
+	return writeSummary(w, summary, markers)
+}
+
+func (f *txtFormatter) writeFile(w *bufio.Writer, entry fileEntry, content []byte) error {
+	chunks := [][]byte{content}
+	if f.chunkTokens > 0 {
+		chunks = chunkContent(content, entry.RelativePath, f.chunkTokens*bytesPerToken, f.respectSymbols)
+	}
+	for i, chunk := range chunks {
+		fields := ""
+		if len(chunks) > 1 {
+			fields = fmt.Sprintf(chunkFieldFormat, i+1, len(chunks))
+		}
+		if f.escape {
+			var escaped bool
+			if chunk, escaped = f.activeMarkers().escape(chunk); escaped {
+				fields += escapedField
+			}
+		}
+		metadata := ""
+		switch {
+		case f.frontMatter:
+			metadata = fileFrontMatter(entry, chunk)
+		case f.metadata:
+			metadata = fileMetadata(entry, chunk, f.activeMarkers())
+		}
+		if entry.Functions != nil && !f.frontMatter {
+			metadata += f.activeMarkers().comment(functionsLine(entry.Functions))
+		}
+		if err := f.writeBegin(w, entry, fields, metadata); err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		if err := f.writeEnd(w, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *txtFormatter) writeError(w *bufio.Writer, entry fileEntry, readErr error) error {
+	if err := f.writeBegin(w, entry, "", ""); err != nil {
+		return err
+	}
+	_, err := w.WriteString(f.activeMarkers().comment(fmt.Sprintf("Error reading %s: %v", entry.RelativePath, readErr)))
+	return err
+}
+
+// collides reports whether content would leave an unescaped marker in the output
+func (f *txtFormatter) collides(content []byte) bool {
+	return !f.escape && f.activeMarkers().collides(content)
+}
+
+// activeMarkers returns the markers in use
+func (f *txtFormatter) activeMarkers() fileMarkers {
+	if f.markers.begin == "" {
+		return markersFor("", "")
+	}
+	return f.markers
+}
+
+// writeBegin writes the BEGIN FILE marker with any extra header fields and metadata
+// lines, preceded by the block separator if one is set. The blank line after the
+// header always separates it from the content.
+func (f *txtFormatter) writeBegin(w *bufio.Writer, entry fileEntry, fields string, metadata string) error {
+	defer func() { f.blocks++ }()
+	header := f.markerPath(entry) + diffStatField(entry) + changeCountField(entry) + fields
+	if f.encodePaths {
+		header += originalPathField + entry.RelativePath
+	}
+	markers := f.activeMarkers()
+	header = markers.begin + markers.commentText(header) + markers.suffix + "\n" + metadata + "\n"
+	if f.separator == nil {
+		_, err := w.WriteString("\n\n" + header)
+		return err
+	}
+	if f.blocks > 0 {
+		if _, err := w.WriteString(*f.separator); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString(header)
+	return err
+}
+
+// fileMetadata renders the -metadata block for a file as "key: value" comment lines
+// in the style of markers. The size and checksum describe the content as written,
+// after transforms.
+func fileMetadata(entry fileEntry, content []byte, markers fileMarkers) string {
+	var b strings.Builder
+	b.WriteString(markers.comment(fmt.Sprintf("size: %d", len(content))))
+	if lang := languageFor(entry.RelativePath).Name; lang != "" {
+		b.WriteString(markers.comment("language: " + lang))
+	}
+	b.WriteString(markers.comment(fmt.Sprintf("sha256: %x", sha256.Sum256(content))))
+	return b.String()
+}
+
+// fileFrontMatter renders the -rag-mode front matter for a file: "key: value" lines
+// between "---" fences, with strings JSON-quoted so the block also reads as YAML.
+// Like -metadata, size and checksum describe the content as written.
+func fileFrontMatter(entry fileEntry, content []byte) string {
+	quote := func(s string) string {
+		data, _ := json.Marshal(s)
+		return string(data)
+	}
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "path: %s\n", quote(filepath.ToSlash(entry.RelativePath)))
+	fmt.Fprintf(&b, "language: %s\n", quote(languageFor(entry.RelativePath).Name))
+	fmt.Fprintf(&b, "size: %d\n", len(content))
+	fmt.Fprintf(&b, "sha256: %s\n", quote(fmt.Sprintf("%x", sha256.Sum256(content))))
+	fmt.Fprintf(&b, "transformed: %t\n", entry.Transformed)
+	if entry.Functions != nil {
+		functions, _ := json.Marshal(entry.Functions)
+		fmt.Fprintf(&b, "functions: %s\n", functions)
+	}
+	b.WriteString("---\n")
+	return b.String()
+}
+
+// markerPath is the path written in the BEGIN and END FILE markers
+func (f *txtFormatter) markerPath(entry fileEntry) string {
+	if f.encodePaths {
+		return encodeMarkerPath(entry.RelativePath)
+	}
+	return entry.RelativePath
+}
+
+// Field appended to the BEGIN FILE marker by -encode-paths. It is always last, so
+// the original path may contain spaces.
+const originalPathField = " original-path="
+
+// encodeMarkerPath percent-encodes each segment of a relative path, keeping forward
+// slashes as separators, so the marker never contains spaces or other special characters
+func encodeMarkerPath(relativePath string) string {
+	segments := strings.Split(filepath.ToSlash(relativePath), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// writeEnd writes the END FILE marker, followed by a blank line unless a block separator is set
+func (f *txtFormatter) writeEnd(w *bufio.Writer, entry fileEntry) error {
+	trailer := "\n"
+	if f.separator == nil {
+		trailer = "\n\n"
+	}
+	markers := f.activeMarkers()
+	_, err := w.WriteString("\n\n" + markers.end + markers.commentText(f.markerPath(entry)) + markers.suffix + trailer)
+	return err
+}
+
+// unescapeSeparator expands the escape sequences accepted by -block-separator
+func unescapeSeparator(value string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\t`, "\t").Replace(value)
+}
+
+// orgFormatter writes an Emacs Org-mode document with one heading and source block per file
+type orgFormatter struct {
+	title string
+	date  time.Time
+}
+
+func (f orgFormatter) begin(w *bufio.Writer) error {
+	_, err := fmt.Fprintf(w, "#+TITLE: %s\n#+DATE: %s\n#+AUTHOR: Colligo\n", f.title, f.date.Format("2006-01-02"))
+	return err
+}
+
+func (orgFormatter) end(w *bufio.Writer, summary *runStats) error {
+	if summary == nil {
+		return nil
+	}
+	return writeSummary(w, summary, markersFor("", ""))
+}
+
+func (orgFormatter) writeFile(w *bufio.Writer, entry fileEntry, content []byte) error {
+	lang := languageFor(entry.RelativePath).Name
+	if lang == "" {
+		lang = "text"
+	}
+	if _, err := fmt.Fprintf(w, "\n* %s\n#+begin_src %s\n", filepath.ToSlash(entry.RelativePath), lang); err != nil {
+		return err
+	}
+	if _, err := w.WriteString(escapeOrgBlock(string(content))); err != nil {
+		return err
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString("#+end_src\n")
+	return err
+}
+
+func (orgFormatter) writeError(w *bufio.Writer, entry fileEntry, readErr error) error {
+	_, err := fmt.Fprintf(w, "\n* %s\nError reading file: %v\n", filepath.ToSlash(entry.RelativePath), readErr)
+	return err
+}
+
+// escapeOrgBlock protects lines that Org would otherwise parse inside a source block,
+// using the same comma escape as org-escape-code-in-string
+func escapeOrgBlock(content string) string {
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "#+") || strings.HasPrefix(trimmed, ",*") || strings.HasPrefix(trimmed, ",#+") {
+			indent := line[:len(line)-len(trimmed)]
+			lines[i] = indent + "," + trimmed
+		}
+	}
+	return strings.Join(lines, "")
+}
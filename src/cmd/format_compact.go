@@ -0,0 +1,81 @@
+// File: src/cmd/format_compact.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// compactFormatter writes grep -rn style output: every line of every file prefixed
+// with its path and line number, with no markers between files. Transforms can drop
+// or merge lines, so the lines of a transformed file get "-" for their number. The
+// doc summary line is not part of the file and gets 0.
+type compactFormatter struct{}
+
+// Stands in for the path of the summary lines. Paths with a space are quoted, so no
+// file can have this prefix.
+const compactSummaryPrefix = "# SUMMARY"
+
+func (compactFormatter) begin(w *bufio.Writer) error { return nil }
+
+func (compactFormatter) end(w *bufio.Writer, summary *runStats) error {
+	if summary == nil {
+		return nil
+	}
+	for i, line := range summaryLines(summary) {
+		if _, err := fmt.Fprintf(w, "%s:%d:%s\n", compactSummaryPrefix, i+1, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (compactFormatter) writeFile(w *bufio.Writer, entry fileEntry, content []byte) error {
+	prefix := compactPath(entry.RelativePath)
+	summary, content := splitDocSummary(content, entry)
+	if summary != nil {
+		if _, err := fmt.Fprintf(w, "%s:0:%s", prefix, summary); err != nil {
+			return err
+		}
+	}
+	// A final newline ends the last line rather than starting another one
+	content = bytes.TrimSuffix(content, []byte("\n"))
+	if len(content) == 0 {
+		return nil
+	}
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		number := strconv.Itoa(i + 1)
+		if entry.Transformed {
+			number = "-"
+		}
+		if _, err := fmt.Fprintf(w, "%s:%s:", prefix, number); err != nil {
+			return err
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (compactFormatter) writeError(w *bufio.Writer, entry fileEntry, readErr error) error {
+	_, err := fmt.Fprintf(w, "%s:0:# Error reading file: %v\n", compactPath(entry.RelativePath), readErr)
+	return err
+}
+
+// compactPath is the path prefix of a file's lines. Paths containing a colon, a
+// quote or whitespace are written as a Go quoted string so the prefix stays unambiguous.
+func compactPath(relativePath string) string {
+	path := filepath.ToSlash(relativePath)
+	if strings.ContainsAny(path, ":\"\\ \t\r\n") {
+		return strconv.Quote(path)
+	}
+	return path
+}
@@ -0,0 +1,89 @@
+// File: src/cmd/format_html.go
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// htmlFormatter writes a standalone HTML page with a section per file. Text files
+// go in a code block; binary files get a placeholder, except images when
+// inlineImages embeds them as data URIs.
+type htmlFormatter struct {
+	title        string
+	inlineImages bool
+}
+
+func (f htmlFormatter) begin(w *bufio.Writer) error {
+	title := html.EscapeString(f.title)
+	_, err := fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n", title, title)
+	return err
+}
+
+func (htmlFormatter) end(w *bufio.Writer, summary *runStats) error {
+	if summary != nil {
+		lines := html.EscapeString(strings.Join(summaryLines(summary), "\n"))
+		if _, err := fmt.Fprintf(w, "<section id=\"summary\">\n<h2>Summary</h2>\n<pre>%s\n</pre>\n</section>\n", lines); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString("</body>\n</html>\n")
+	return err
+}
+
+func (f htmlFormatter) writeFile(w *bufio.Writer, entry fileEntry, content []byte) error {
+	if err := writeHTMLSectionStart(w, entry); err != nil {
+		return err
+	}
+	var err error
+	switch mimeType := contentMIMEType(entry.RelativePath, content); {
+	case strings.HasPrefix(mimeType, "image/") && f.inlineImages:
+		_, err = fmt.Fprintf(w, "<img src=\"data:%s;base64,%s\" alt=\"%s\">\n",
+			mimeType, base64.StdEncoding.EncodeToString(content), html.EscapeString(filepath.ToSlash(entry.RelativePath)))
+	case !strings.HasPrefix(mimeType, "text/"):
+		_, err = fmt.Fprintf(w, "<p><em>Binary file (%s, %d bytes) not shown</em></p>\n", html.EscapeString(mimeType), len(content))
+	default:
+		class := ""
+		if lang := languageFor(entry.RelativePath).Name; lang != "" {
+			class = fmt.Sprintf(" class=\"language-%s\"", lang)
+		}
+		_, err = fmt.Fprintf(w, "<pre><code%s>%s</code></pre>\n", class, html.EscapeString(string(content)))
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.WriteString("</section>\n")
+	return err
+}
+
+func (htmlFormatter) writeError(w *bufio.Writer, entry fileEntry, readErr error) error {
+	if err := writeHTMLSectionStart(w, entry); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "<p><em>Error reading file: %s</em></p>\n</section>\n", html.EscapeString(readErr.Error()))
+	return err
+}
+
+// writeHTMLSectionStart opens a file's section. Its id is the same heading anchor
+// -rewrite-md-links points links at.
+func writeHTMLSectionStart(w *bufio.Writer, entry fileEntry) error {
+	path := filepath.ToSlash(entry.RelativePath)
+	_, err := fmt.Fprintf(w, "<section id=\"%s\">\n<h2>%s</h2>\n", html.EscapeString(headingAnchor(path)), html.EscapeString(path))
+	return err
+}
+
+// contentMIMEType sniffs the MIME type of content, without parameters. SVG sniffs
+// as text, so an image type implied by the extension takes precedence.
+func contentMIMEType(relativePath string, content []byte) string {
+	if byExtension := mime.TypeByExtension(strings.ToLower(filepath.Ext(relativePath))); strings.HasPrefix(byExtension, "image/svg") {
+		return "image/svg+xml"
+	}
+	mimeType, _, _ := strings.Cut(http.DetectContentType(content), ";")
+	return mimeType
+}
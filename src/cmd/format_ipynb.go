@@ -0,0 +1,102 @@
+// File: src/cmd/format_ipynb.go
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// notebookCell is one cell of a Jupyter notebook in nbformat 4.5
+type notebookCell struct {
+	CellType string         `json:"cell_type"`
+	ID       string         `json:"id"`
+	Metadata map[string]any `json:"metadata"`
+	Source   []string       `json:"source"`
+}
+
+// ipynbFormatter writes a Jupyter notebook with a Markdown heading cell and a raw
+// cell per file. Like the JSON format, cells are streamed one per line.
+type ipynbFormatter struct {
+	repository string
+	cells      int
+}
+
+func (f *ipynbFormatter) begin(w *bufio.Writer) error {
+	_, err := w.WriteString("{\n \"cells\": [")
+	return err
+}
+
+func (f *ipynbFormatter) end(w *bufio.Writer, summary *runStats) error {
+	if summary != nil {
+		source := "## Summary\n\n```\n" + strings.Join(summaryLines(summary), "\n") + "\n```\n"
+		if err := f.writeCell(w, "markdown", nil, source); err != nil {
+			return err
+		}
+	}
+
+	closing := "\n ]"
+	if f.cells == 0 {
+		closing = "]"
+	}
+	metadata, err := json.Marshal(map[string]any{"title": f.repository})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s,\n \"metadata\": %s,\n \"nbformat\": 4,\n \"nbformat_minor\": 5\n}\n", closing, metadata)
+	return err
+}
+
+func (f *ipynbFormatter) writeFile(w *bufio.Writer, entry fileEntry, content []byte) error {
+	path := filepath.ToSlash(entry.RelativePath)
+	if err := f.writeCell(w, "markdown", nil, "## "+path); err != nil {
+		return err
+	}
+	return f.writeCell(w, "raw", map[string]any{"source": path}, string(content))
+}
+
+func (f *ipynbFormatter) writeError(w *bufio.Writer, entry fileEntry, readErr error) error {
+	path := filepath.ToSlash(entry.RelativePath)
+	if err := f.writeCell(w, "markdown", nil, "## "+path); err != nil {
+		return err
+	}
+	return f.writeCell(w, "raw", map[string]any{"source": path}, fmt.Sprintf("Error reading %s: %v\n", path, readErr))
+}
+
+// writeCell writes one element of the cells array. Cell IDs only need to be unique
+// within the notebook, so they are numbered.
+func (f *ipynbFormatter) writeCell(w *bufio.Writer, cellType string, metadata map[string]any, source string) error {
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	f.cells++
+	data, err := json.Marshal(notebookCell{
+		CellType: cellType,
+		ID:       fmt.Sprintf("cell-%d", f.cells),
+		Metadata: metadata,
+		Source:   notebookSource(source),
+	})
+	if err != nil {
+		return err
+	}
+	separator := ",\n  "
+	if f.cells == 1 {
+		separator = "\n  "
+	}
+	if _, err := w.WriteString(separator); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// notebookSource splits text into lines that keep their newlines, as notebooks store them
+func notebookSource(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,113 @@
+// File: src/cmd/format_json.go
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+)
+
+// jsonFileRecord is the JSON representation of one file in the output
+type jsonFileRecord struct {
+	Path     string `json:"path"`
+	Language string `json:"language,omitempty"`
+	Size     int64  `json:"size"`
+	Content  string `json:"content"`
+	// LineOffsets holds the byte offset in content where each line starts, with
+	// -json-line-offsets; it is omitted for empty files
+	LineOffsets []int  `json:"lineOffsets,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// jsonFormatter writes a single JSON document with one record per file. Records are
+// streamed one per line so large repositories never need to be held in memory.
+type jsonFormatter struct {
+	repository  string
+	lineOffsets bool
+	records     int
+}
+
+func (f *jsonFormatter) begin(w *bufio.Writer) error {
+	name, err := json.Marshal(f.repository)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "{\n  \"repository\": %s,\n  \"files\": [", name)
+	return err
+}
+
+func (f *jsonFormatter) end(w *bufio.Writer, summary *runStats) error {
+	closing := "\n  ]"
+	if f.records == 0 {
+		closing = "]"
+	}
+	if _, err := w.WriteString(closing); err != nil {
+		return err
+	}
+	if summary != nil {
+		data, err := json.Marshal(summary)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, ",\n  \"summary\": %s", data); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString("\n}\n")
+	return err
+}
+
+func (f *jsonFormatter) writeFile(w *bufio.Writer, entry fileEntry, content []byte) error {
+	record := jsonFileRecord{
+		Path:     filepath.ToSlash(entry.RelativePath),
+		Language: languageFor(entry.RelativePath).Name,
+		Size:     entry.Size,
+		Content:  string(content),
+	}
+	if f.lineOffsets {
+		record.LineOffsets = lineOffsets(content)
+	}
+	return f.writeRecord(w, record)
+}
+
+// lineOffsets returns the byte offset of the start of each line of content. A
+// trailing newline ends the last line rather than starting an empty one.
+func lineOffsets(content []byte) []int {
+	if len(content) == 0 {
+		return nil
+	}
+	offsets := []int{0}
+	for i, b := range content[:len(content)-1] {
+		if b == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+func (f *jsonFormatter) writeError(w *bufio.Writer, entry fileEntry, readErr error) error {
+	return f.writeRecord(w, jsonFileRecord{
+		Path:  filepath.ToSlash(entry.RelativePath),
+		Size:  entry.Size,
+		Error: readErr.Error(),
+	})
+}
+
+// writeRecord writes one element of the files array
+func (f *jsonFormatter) writeRecord(w *bufio.Writer, record jsonFileRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	separator := ",\n    "
+	if f.records == 0 {
+		separator = "\n    "
+	}
+	f.records++
+	if _, err := w.WriteString(separator); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
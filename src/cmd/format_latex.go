@@ -0,0 +1,177 @@
+// File: src/cmd/format_latex.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Names the listings package knows for Colligo's languages. Languages listings does
+// not define, such as Go, get no language option so the document still compiles.
+var listingsLanguages = map[string]string{
+	"c":      "C",
+	"cpp":    "C++",
+	"csharp": "{[Sharp]C}",
+	"java":   "Java",
+	"php":    "PHP",
+	"python": "Python",
+	"ruby":   "Ruby",
+	"perl":   "Perl",
+	"r":      "R",
+	"bash":   "bash",
+	"sql":    "SQL",
+	"lua":    "Lua",
+	"html":   "HTML",
+	"xml":    "XML",
+}
+
+// latexFormatter writes a LaTeX document with a subsection and lstlisting
+// environment per file, for code appendices in papers
+type latexFormatter struct {
+	title string
+	// defined holds the listing environments declared so far for files whose
+	// content would end an lstlisting early
+	defined map[string]bool
+}
+
+func (f *latexFormatter) begin(w *bufio.Writer) error {
+	_, err := fmt.Fprintf(w, "\\documentclass{article}\n"+
+		"\\usepackage[T1]{fontenc}\n"+
+		"\\usepackage[utf8]{inputenc}\n"+
+		"\\usepackage{listings,xcolor}\n"+
+		"\\lstset{basicstyle=\\ttfamily\\small, breaklines=true, columns=fullflexible, keepspaces=true, commentstyle=\\color{gray}}\n"+
+		"\\title{%s}\n"+
+		"\\begin{document}\n"+
+		"\\maketitle\n", latexEscape(f.title))
+	return err
+}
+
+func (*latexFormatter) end(w *bufio.Writer, summary *runStats) error {
+	if summary != nil {
+		lines := strings.Join(summaryLines(summary), "\n")
+		if _, err := fmt.Fprintf(w, "\n\\section*{Summary}\n\\begin{verbatim}\n%s\n\\end{verbatim}\n", lines); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString("\\end{document}\n")
+	return err
+}
+
+func (f *latexFormatter) writeFile(w *bufio.Writer, entry fileEntry, content []byte) error {
+	if err := writeLatexSubsection(w, entry); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(contentMIMEType(entry.RelativePath, content), "text/") {
+		_, err := fmt.Fprintf(w, "\\textit{Binary file (%d bytes) not shown}\n", len(content))
+		return err
+	}
+	var options []string
+	if name, ok := listingsLanguages[languageFor(entry.RelativePath).Name]; ok {
+		options = append(options, "language="+name)
+	}
+	if literate := listingsLiterate(content); literate != "" {
+		options = append(options, "literate="+literate)
+	}
+	env, err := f.listingEnvironment(w, content)
+	if err != nil {
+		return err
+	}
+	begin := "\\begin{" + env + "}"
+	if len(options) > 0 {
+		begin += "[" + strings.Join(options, ", ") + "]"
+	}
+	if _, err := w.WriteString(begin + "\n"); err != nil {
+		return err
+	}
+	if _, err := w.Write(content); err != nil {
+		return err
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	_, err = w.WriteString("\\end{" + env + "}\n")
+	return err
+}
+
+// listingEnvironment returns the environment to wrap content in. Listings end at
+// the first \end{name} in their content, so content holding \end{lstlisting} gets
+// an equivalent environment, declared on first use, whose end it does not contain.
+func (f *latexFormatter) listingEnvironment(w *bufio.Writer, content []byte) (string, error) {
+	env := "lstlisting"
+	for bytes.Contains(content, []byte("\\end{"+env+"}")) {
+		if env == "lstlisting" {
+			env = "colligolisting"
+		} else {
+			env += "x"
+		}
+	}
+	if env == "lstlisting" || f.defined[env] {
+		return env, nil
+	}
+	if f.defined == nil {
+		f.defined = make(map[string]bool)
+	}
+	f.defined[env] = true
+	_, err := w.WriteString("\\lstnewenvironment{" + env + "}[1][]{\\lstset{#1}}{}\n")
+	return env, err
+}
+
+// listingsLiterate maps every non-ASCII character in content to itself as text, so
+// listings, which reads its input byte by byte, hands whole UTF-8 characters to
+// inputenc. Characters the document's fonts lack still fail to typeset.
+func listingsLiterate(content []byte) string {
+	seen := make(map[rune]bool)
+	var runes []rune
+	for _, r := range string(content) {
+		if r >= utf8.RuneSelf && r != utf8.RuneError && !seen[r] {
+			seen[r] = true
+			runes = append(runes, r)
+		}
+	}
+	slices.Sort(runes)
+	var b strings.Builder
+	for _, r := range runes {
+		fmt.Fprintf(&b, "{%c}{{%c}}1", r, r)
+	}
+	return b.String()
+}
+
+func (*latexFormatter) writeError(w *bufio.Writer, entry fileEntry, readErr error) error {
+	if err := writeLatexSubsection(w, entry); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\\textit{Error reading file: %s}\n", latexEscape(readErr.Error()))
+	return err
+}
+
+// writeLatexSubsection opens a file's subsection, titled with its relative path
+func writeLatexSubsection(w *bufio.Writer, entry fileEntry) error {
+	_, err := fmt.Fprintf(w, "\n\\subsection{%s}\n", latexEscape(filepath.ToSlash(entry.RelativePath)))
+	return err
+}
+
+// Replacements for the characters LaTeX treats specially in running text
+var latexReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`$`, `\$`,
+	`&`, `\&`,
+	`#`, `\#`,
+	`%`, `\%`,
+	`_`, `\_`,
+	`^`, `\textasciicircum{}`,
+	`~`, `\textasciitilde{}`,
+)
+
+// latexEscape makes s safe to use as LaTeX text
+func latexEscape(s string) string {
+	return latexReplacer.Replace(s)
+}
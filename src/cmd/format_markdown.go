@@ -0,0 +1,64 @@
+// File: src/cmd/format_markdown.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// markdownFormatter writes a Markdown document with a heading and fenced code block per file
+type markdownFormatter struct {
+	title string
+}
+
+func (f markdownFormatter) begin(w *bufio.Writer) error {
+	_, err := fmt.Fprintf(w, "# %s\n", f.title)
+	return err
+}
+
+func (markdownFormatter) end(w *bufio.Writer, summary *runStats) error {
+	if summary == nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "\n## Summary\n\n```\n%s\n```\n", strings.Join(summaryLines(summary), "\n"))
+	return err
+}
+
+func (markdownFormatter) writeFile(w *bufio.Writer, entry fileEntry, content []byte) error {
+	fence := markdownFence(string(content))
+	if _, err := fmt.Fprintf(w, "\n## %s\n\n%s%s\n", filepath.ToSlash(entry.RelativePath), fence, languageFor(entry.RelativePath).Name); err != nil {
+		return err
+	}
+	if _, err := w.Write(content); err != nil {
+		return err
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString(fence + "\n")
+	return err
+}
+
+func (markdownFormatter) writeError(w *bufio.Writer, entry fileEntry, readErr error) error {
+	_, err := fmt.Fprintf(w, "\n## %s\n\n_Error reading file: %v_\n", filepath.ToSlash(entry.RelativePath), readErr)
+	return err
+}
+
+// markdownFence returns a backtick fence longer than any backtick run in content, so
+// files that themselves contain fenced code blocks cannot end the block early
+func markdownFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
@@ -0,0 +1,123 @@
+// File: src/cmd/format_slack.go
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Slack's limit on the text of one attachment
+const slackTextLimit = 2000
+
+// slackMessage is a Slack message payload with Block Kit blocks and legacy attachments
+type slackMessage struct {
+	Blocks      []slackBlock      `json:"blocks"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+// slackBlock is a section block with a single mrkdwn text object
+type slackBlock struct {
+	Type string    `json:"type"`
+	Text slackText `json:"text"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackAttachment carries one file
+type slackAttachment struct {
+	Title  string `json:"title"`
+	Text   string `json:"text"`
+	Footer string `json:"footer"`
+	Color  string `json:"color,omitempty"`
+}
+
+// slackFormatter writes a Slack message payload with one attachment per file. The
+// footer and first block need the file count, so attachments are held until the end;
+// each is capped at slackTextLimit characters, which bounds the memory used.
+type slackFormatter struct {
+	repository  string
+	repoPath    string
+	attachments []slackAttachment
+}
+
+func (f *slackFormatter) begin(w *bufio.Writer) error { return nil }
+
+func (f *slackFormatter) end(w *bufio.Writer, summary *runStats) error {
+	footer := fmt.Sprintf("%d files from %s", len(f.attachments), f.repoPath)
+	for i := range f.attachments {
+		f.attachments[i].Footer = footer
+	}
+
+	// The names are cut to a quarter of the limit each, leaving the summary at least
+	// the rest of the block. Only the raw text is cut, so the markup stays whole.
+	text := fmt.Sprintf("*%s*: %d files combined from `%s`", slackEscapeText(f.repository, slackTextLimit/4), len(f.attachments), slackEscapeText(f.repoPath, slackTextLimit/4))
+	if summary != nil {
+		const open, close = "\n```\n", "\n```"
+		room := slackTextLimit - utf8.RuneCountInString(text+open+close)
+		text += open + slackEscapeText(strings.Join(summaryLines(summary), "\n"), room) + close
+	}
+	message := slackMessage{
+		Blocks:      []slackBlock{{Type: "section", Text: slackText{Type: "mrkdwn", Text: text}}},
+		Attachments: f.attachments,
+	}
+	if message.Attachments == nil {
+		message.Attachments = []slackAttachment{}
+	}
+
+	data, err := json.MarshalIndent(message, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
+
+func (f *slackFormatter) writeFile(w *bufio.Writer, entry fileEntry, content []byte) error {
+	f.attachments = append(f.attachments, slackAttachment{
+		Title: filepath.ToSlash(entry.RelativePath),
+		Text:  slackEscapeText(string(content), slackTextLimit),
+	})
+	return nil
+}
+
+func (f *slackFormatter) writeError(w *bufio.Writer, entry fileEntry, readErr error) error {
+	f.attachments = append(f.attachments, slackAttachment{
+		Title: filepath.ToSlash(entry.RelativePath),
+		Text:  slackEscapeText(fmt.Sprintf("Error reading file: %v", readErr), slackTextLimit),
+		Color: "danger",
+	})
+	return nil
+}
+
+// Slack's mrkdwn reads &, < and > as the start of entities and links, so text
+// must escape them
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackEscapeText escapes text for mrkdwn and cuts the result to at most limit
+// characters, ending with an ellipsis when anything was cut. The cut falls between
+// escapes, never inside one.
+func slackEscapeText(text string, limit int) string {
+	escaped := slackEscaper.Replace(text)
+	if utf8.RuneCountInString(escaped) <= limit {
+		return escaped
+	}
+	var b strings.Builder
+	length := 0
+	for _, r := range text {
+		piece := slackEscaper.Replace(string(r))
+		if length += utf8.RuneCountInString(piece); length > limit-1 {
+			break
+		}
+		b.WriteString(piece)
+	}
+	return b.String() + "…"
+}
@@ -0,0 +1,575 @@
+// File: src/cmd/format_test.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestOrgFormat checks the Org-mode document produced for a minimal repository
+func TestOrgFormat(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"main.go":        "package main\n\nfunc main() {}\n",
+		"docs/notes.txt": "* not a heading\n#+not a keyword",
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "org"})
+	if output == "" {
+		t.Fatalf("Expected a non-empty Org document")
+	}
+
+	for _, header := range []string{"#+TITLE: ", "#+DATE: ", "#+AUTHOR: "} {
+		if !strings.Contains(output, "\n"+header) && !strings.HasPrefix(output, header) {
+			t.Errorf("Expected Org header %q in output", header)
+		}
+	}
+
+	expectedGo := "\n* main.go\n#+begin_src go\npackage main\n\nfunc main() {}\n#+end_src\n"
+	if !strings.Contains(output, expectedGo) {
+		t.Errorf("Expected Go source block %q in output:\n%s", expectedGo, output)
+	}
+
+	expectedText := "\n* docs/notes.txt\n#+begin_src text\n,* not a heading\n,#+not a keyword\n#+end_src\n"
+	if !strings.Contains(output, expectedText) {
+		t.Errorf("Expected escaped text block %q in output:\n%s", expectedText, output)
+	}
+
+	// Every file heading must be a top-level heading
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "*") && !strings.HasPrefix(line, "* ") {
+			t.Errorf("Unexpected heading level in line %q", line)
+		}
+	}
+}
+
+// TestNewFormatterUnknown checks that unknown formats are rejected
+func TestNewFormatterUnknown(t *testing.T) {
+	if _, err := newFormatter("docx", &Config{}, &runState{}); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
+
+// TestTxtErrorCommentStyle checks that the line written for an unreadable file
+// follows -header-comment-style and is skipped by extract
+func TestTxtErrorCommentStyle(t *testing.T) {
+	cases := []struct {
+		style    string
+		expected string
+	}{
+		{"hash", "# Error reading broken.go: permission denied\n"},
+		{"double-slash", "// Error reading broken.go: permission denied\n"},
+		{"html", "<!-- Error reading broken.go: permission denied -->\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.style, func(t *testing.T) {
+			f, err := newFormatter("txt", &Config{HeaderCommentStyle: c.style}, &runState{})
+			if err != nil {
+				t.Fatalf("Failed to create formatter: %v", err)
+			}
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			if err := f.writeError(w, fileEntry{RelativePath: "broken.go"}, errors.New("permission denied")); err != nil {
+				t.Fatalf("Failed to write error: %v", err)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Failed to flush: %v", err)
+			}
+			if !strings.HasSuffix(buf.String(), c.expected) {
+				t.Errorf("Expected the error line %q, got:\n%s", c.expected, buf.String())
+			}
+			extracted, err := parseCombinedOutput(buf.Bytes())
+			if err != nil {
+				t.Fatalf("Failed to parse output: %v", err)
+			}
+			if len(extracted) != 0 {
+				t.Errorf("Expected the unreadable file to be skipped, got %d files", len(extracted))
+			}
+		})
+	}
+}
+
+// TestBlockSeparator checks the text placed between txt blocks
+func TestBlockSeparator(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{"a.txt": "A", "b.txt": "B"})
+
+	cases := []struct {
+		name      string
+		separator *string
+		expected  string
+	}{
+		{"Default", nil, "\n\n# BEGIN FILE: a.txt\n\nA\n\n# END FILE: a.txt\n\n\n\n# BEGIN FILE: b.txt\n\nB\n\n# END FILE: b.txt\n\n"},
+		{"Empty", new(string), "# BEGIN FILE: a.txt\n\nA\n\n# END FILE: a.txt\n# BEGIN FILE: b.txt\n\nB\n\n# END FILE: b.txt\n"},
+		{"Rule", func() *string { s := unescapeSeparator(`---\n`); return &s }(), "# BEGIN FILE: a.txt\n\nA\n\n# END FILE: a.txt\n---\n# BEGIN FILE: b.txt\n\nB\n\n# END FILE: b.txt\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			output := runAndReadOutput(t, &Config{RepoPath: repoDir, BlockSeparator: c.separator})
+			if output != c.expected {
+				t.Errorf("Expected %q but got %q", c.expected, output)
+			}
+		})
+	}
+}
+
+// TestMarkdownFence checks that fences are longer than any backtick run in the content
+func TestMarkdownFence(t *testing.T) {
+	cases := map[string]string{
+		"plain":               "```",
+		"inline `code`":       "```",
+		"```go\nfenced\n```":  "````",
+		"`````\nlong fence\n": "``````",
+	}
+	for content, expected := range cases {
+		if got := markdownFence(content); got != expected {
+			t.Errorf("markdownFence(%q) = %q, expected %q", content, got, expected)
+		}
+	}
+}
+
+// TestJSONFormatEmpty checks that an empty repository still yields valid JSON
+func TestJSONFormatEmpty(t *testing.T) {
+	repoDir := createTempDir(t, "colligo_json_empty")
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "json"})
+
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v:\n%s", err, output)
+	}
+	if files, ok := doc["files"].([]any); !ok || len(files) != 0 {
+		t.Errorf("Expected an empty files array, got %v", doc["files"])
+	}
+}
+
+// TestJSONLineOffsets checks that each record lists where its lines start in the content
+func TestJSONLineOffsets(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"main.go":  "package main\n\nfunc main() {}\n",
+		"no-eol":   "a\nbc",
+		"empty.md": "",
+	})
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "json", JSONLineOffsets: true})
+
+	var doc struct {
+		Files []jsonFileRecord `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v:\n%s", err, output)
+	}
+	expected := map[string][]int{"main.go": {0, 13, 14}, "no-eol": {0, 2}, "empty.md": nil}
+	for _, file := range doc.Files {
+		if !reflect.DeepEqual(file.LineOffsets, expected[file.Path]) {
+			t.Errorf("Expected line offsets %v for %s, got %v", expected[file.Path], file.Path, file.LineOffsets)
+		}
+	}
+	if len(doc.Files) != len(expected) {
+		t.Errorf("Expected %d records, got %d", len(expected), len(doc.Files))
+	}
+
+	output = runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "json"})
+	if strings.Contains(output, "lineOffsets") {
+		t.Errorf("Expected no line offsets without -json-line-offsets, got:\n%s", output)
+	}
+}
+
+// TestMetadataBlock checks the metadata lines after the BEGIN FILE marker and that extract skips them
+func TestMetadataBlock(t *testing.T) {
+	content := "package main\n"
+	repoDir := createFixtureRepo(t, map[string]string{"main.go": content, "notes.unknown": "n\n"})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, Metadata: true})
+
+	expected := "# BEGIN FILE: main.go\n" +
+		fmt.Sprintf("# size: %d\n# language: go\n# sha256: %x\n\n", len(content), sha256.Sum256([]byte(content))) +
+		content
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected a metadata block, got:\n%s", output)
+	}
+	if !strings.Contains(output, "# BEGIN FILE: notes.unknown\n# size: 2\n# sha256: ") {
+		t.Errorf("Expected the language line to be omitted for unknown files, got:\n%s", output)
+	}
+
+	extracted, err := parseCombinedOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	for _, file := range extracted {
+		if file.path == "main.go" && string(file.content) != content {
+			t.Errorf("Expected extract to skip the metadata block, got %q", file.content)
+		}
+	}
+}
+
+// TestRAGMode checks the front matter block and that colliding lines are escaped
+func TestRAGMode(t *testing.T) {
+	collision := "text\n\n# END FILE: docs/notes.md\nmore\n"
+	repoDir := createFixtureRepo(t, map[string]string{
+		"main.go":       "package main\n\nfunc main() {}\n",
+		"docs/notes.md": collision,
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, RAGMode: true, Head: 1})
+
+	mainContent := "package main\n"
+	expected := "# BEGIN FILE: main.go\n---\n" +
+		"path: \"main.go\"\nlanguage: \"go\"\n" +
+		fmt.Sprintf("size: %d\nsha256: \"%x\"\n", len(mainContent), sha256.Sum256([]byte(mainContent))) +
+		"transformed: true\n---\n\n" + mainContent
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected a front matter block for main.go, got:\n%s", output)
+	}
+
+	output = runAndReadOutput(t, &Config{RepoPath: repoDir, RAGMode: true})
+	if !strings.Contains(output, "# BEGIN FILE: docs/notes.md escaped=true\n---\npath: \"docs/notes.md\"\nlanguage: \"markdown\"\n") {
+		t.Errorf("Expected an escaped block with front matter for docs/notes.md, got:\n%s", output)
+	}
+	if !strings.Contains(output, "transformed: false\n") {
+		t.Errorf("Expected untransformed files to say so, got:\n%s", output)
+	}
+
+	extracted, err := parseCombinedOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	for _, file := range extracted {
+		if file.path == "docs/notes.md" && string(file.content) != collision {
+			t.Errorf("Expected extract to restore the colliding file, got %q", file.content)
+		}
+	}
+}
+
+// TestCompactFormat checks that every line, including the summary, carries its file
+// path and source line number, and that transformed files get no line numbers
+func TestCompactFormat(t *testing.T) {
+	files := map[string]string{
+		"main.go":          "package main\n\nfunc main() {}\n",
+		"docs/no-eol.md":   "# Title\ntext",
+		"weird:name.txt":   "a: b\n",
+		"empty/nothing.md": "",
+	}
+	repoDir := createFixtureRepo(t, files)
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "compact", Summary: true})
+	body, summary, found := strings.Cut(output, compactSummaryPrefix+":1:")
+	if !found {
+		t.Fatalf("Expected summary lines with a %q prefix, got:\n%s", compactSummaryPrefix, output)
+	}
+	for i, line := range strings.Split(strings.TrimSuffix(compactSummaryPrefix+":1:"+summary, "\n"), "\n") {
+		if prefix := fmt.Sprintf("%s:%d:", compactSummaryPrefix, i+1); !strings.HasPrefix(line, prefix) {
+			t.Errorf("Expected summary line %q to start with %q", line, prefix)
+		}
+	}
+	output = body
+
+	prefixes := map[string]string{
+		"main.go":        "main.go",
+		"docs/no-eol.md": "docs/no-eol.md",
+		"weird:name.txt": `"weird:name.txt"`,
+	}
+	got := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		matched := false
+		for path, prefix := range prefixes {
+			rest, ok := strings.CutPrefix(line, prefix+":")
+			if !ok {
+				continue
+			}
+			number, text, ok := strings.Cut(rest, ":")
+			if !ok || number != fmt.Sprint(len(got[path])+1) {
+				t.Errorf("Expected line %d of %s, got %q", len(got[path])+1, path, line)
+			}
+			got[path] = append(got[path], text)
+			matched = true
+		}
+		if !matched {
+			t.Errorf("Expected every line to start with a known path, got %q", line)
+		}
+	}
+	for path := range prefixes {
+		expected := strings.Split(strings.TrimSuffix(files[path], "\n"), "\n")
+		if !reflect.DeepEqual(got[path], expected) {
+			t.Errorf("Expected the lines of %s to be %q, got %q", path, expected, got[path])
+		}
+	}
+
+	repoDir = createFixtureRepo(t, map[string]string{"lib.go": "package lib\n\n// F does nothing\nfunc F() {}\n"})
+	transformed := runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "compact", StripComments: true})
+	if transformed != "lib.go:-:package lib\nlib.go:-:\nlib.go:-:func F() {}\n" {
+		t.Errorf("Expected the lines of a transformed file without numbers, got:\n%s", transformed)
+	}
+
+	// The doc summary is not a line of the file, so it does not hide the numbers
+	summarized := runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "compact", EmitGoDocSummaries: true})
+	if summarized != "lib.go:0:// SUMMARY: (no documentation)\nlib.go:1:package lib\nlib.go:2:\nlib.go:3:// F does nothing\nlib.go:4:func F() {}\n" {
+		t.Errorf("Expected the summary on line 0 and numbered file lines, got:\n%s", summarized)
+	}
+	summarized = runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "compact", EmitGoDocSummaries: true, StripComments: true, TransformOrder: []string{"go-doc-summary"}})
+	if !strings.HasPrefix(summarized, "lib.go:0:// SUMMARY: (no documentation)\nlib.go:-:package lib\n") {
+		t.Errorf("Expected stripping comments after the summary to keep it, got:\n%s", summarized)
+	}
+}
+
+// TestHTMLInlineImages checks that images become data URIs holding the original bytes
+func TestHTMLInlineImages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	repoDir := createFixtureRepo(t, map[string]string{
+		"logo.png": pngData.String(),
+		"data.bin": "\x00\x01\x02binary",
+		"main.go":  "package main // <b>\n",
+	})
+
+	// Text transforms and sanitizing must leave binary files alone
+	for _, cfg := range []*Config{
+		{RepoPath: repoDir, Format: "html", InlineImages: true},
+		{RepoPath: repoDir, Format: "html", InlineImages: true, Head: 1, SanitizeUTF8: "replace"},
+	} {
+		output := runAndReadOutput(t, cfg)
+
+		match := regexp.MustCompile(`<img src="data:image/png;base64,([A-Za-z0-9+/=]+)" alt="logo.png">`).FindStringSubmatch(output)
+		if match == nil {
+			t.Fatalf("Expected an inline PNG, got:\n%s", output)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(match[1])
+		if err != nil || !bytes.Equal(decoded, pngData.Bytes()) {
+			t.Errorf("Expected the data URI to decode to the original PNG (err %v)", err)
+		}
+		if !strings.Contains(output, "<section id=\"databin\">\n<h2>data.bin</h2>\n<p><em>Binary file (application/octet-stream, 9 bytes) not shown</em></p>") {
+			t.Errorf("Expected a placeholder for the non-image binary, got:\n%s", output)
+		}
+		if !strings.Contains(output, "<pre><code class=\"language-go\">package main // &lt;b&gt;\n</code></pre>") {
+			t.Errorf("Expected escaped source in a code block, got:\n%s", output)
+		}
+	}
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "html"})
+	if strings.Contains(output, "<img") || !strings.Contains(output, "<p><em>Binary file (image/png, ") {
+		t.Errorf("Expected images to be placeholders without -inline-images, got:\n%s", output)
+	}
+}
+
+// TestIpynbFormat checks that the notebook is valid nbformat 4.5 with two cells per file
+func TestIpynbFormat(t *testing.T) {
+	files := map[string]string{
+		"main.go":       "package main\n\nfunc main() {}\n",
+		"docs/notes.md": "# Notes",
+		"empty.txt":     "",
+	}
+	repoDir := createFixtureRepo(t, files)
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "ipynb"})
+
+	var notebook struct {
+		Cells []struct {
+			CellType string         `json:"cell_type"`
+			ID       string         `json:"id"`
+			Metadata map[string]any `json:"metadata"`
+			Source   []string       `json:"source"`
+		} `json:"cells"`
+		Metadata      map[string]any `json:"metadata"`
+		Nbformat      int            `json:"nbformat"`
+		NbformatMinor int            `json:"nbformat_minor"`
+	}
+	if err := json.Unmarshal([]byte(output), &notebook); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, output)
+	}
+	if notebook.Nbformat != 4 || notebook.NbformatMinor != 5 {
+		t.Errorf("Expected nbformat 4.5, got %d.%d", notebook.Nbformat, notebook.NbformatMinor)
+	}
+	if len(notebook.Cells) != 2*len(files) {
+		t.Fatalf("Expected %d cells, got %d", 2*len(files), len(notebook.Cells))
+	}
+
+	ids := make(map[string]bool)
+	for i := 0; i < len(notebook.Cells); i += 2 {
+		heading, raw := notebook.Cells[i], notebook.Cells[i+1]
+		if heading.CellType != "markdown" || raw.CellType != "raw" {
+			t.Fatalf("Expected a markdown cell followed by a raw cell, got %s and %s", heading.CellType, raw.CellType)
+		}
+		path, _ := raw.Metadata["source"].(string)
+		if strings.Join(heading.Source, "") != "## "+path {
+			t.Errorf("Expected the heading to name %s, got %q", path, heading.Source)
+		}
+		if got := strings.Join(raw.Source, ""); got != files[path] {
+			t.Errorf("Expected the raw cell for %s to hold %q, got %q", path, files[path], got)
+		}
+		for _, id := range []string{heading.ID, raw.ID} {
+			if id == "" || ids[id] {
+				t.Errorf("Expected unique non-empty cell IDs, got %q", id)
+			}
+			ids[id] = true
+		}
+	}
+}
+
+// TestSlackAttachmentFormat checks the Slack payload structure, mrkdwn escaping,
+// truncation and footer
+func TestSlackAttachmentFormat(t *testing.T) {
+	long := strings.Repeat("é", slackTextLimit+50)
+	repoDir := createFixtureRepo(t, map[string]string{
+		"main.go":     "package main\n",
+		"big/ü.txt":   long,
+		"big/amp.txt": strings.Repeat("&", slackTextLimit),
+		"page.html":   "<a href=\"x\">Tom & Jerry</a>\n",
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "slack-attachment", Summary: true})
+
+	var message slackMessage
+	decoder := json.NewDecoder(strings.NewReader(output))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&message); err != nil {
+		t.Fatalf("Output does not match the Slack payload structure: %v\n%s", err, output)
+	}
+
+	if len(message.Blocks) != 1 || message.Blocks[0].Type != "section" || message.Blocks[0].Text.Type != "mrkdwn" {
+		t.Fatalf("Expected a single mrkdwn section block, got %+v", message.Blocks)
+	}
+	if !strings.Contains(message.Blocks[0].Text.Text, "4 files combined from `"+repoDir+"`") {
+		t.Errorf("Expected the repo summary in the first block, got %q", message.Blocks[0].Text.Text)
+	}
+
+	if len(message.Attachments) != 4 {
+		t.Fatalf("Expected one attachment per file, got %d", len(message.Attachments))
+	}
+	byTitle := make(map[string]slackAttachment)
+	for _, attachment := range message.Attachments {
+		byTitle[attachment.Title] = attachment
+		if attachment.Footer != "4 files from "+repoDir {
+			t.Errorf("Unexpected footer %q", attachment.Footer)
+		}
+	}
+	if byTitle["main.go"].Text != "package main\n" {
+		t.Errorf("Unexpected text for main.go: %q", byTitle["main.go"].Text)
+	}
+	truncated := []rune(byTitle["big/ü.txt"].Text)
+	if len(truncated) != slackTextLimit || truncated[len(truncated)-1] != '…' {
+		t.Errorf("Expected the long file to be cut to %d characters ending in an ellipsis, got %d", slackTextLimit, len(truncated))
+	}
+	if text := byTitle["page.html"].Text; text != "&lt;a href=\"x\"&gt;Tom &amp; Jerry&lt;/a&gt;\n" {
+		t.Errorf("Expected &, < and > to be escaped, got %q", text)
+	}
+	// The cut falls between escapes, never inside one
+	escaped := byTitle["big/amp.txt"].Text
+	kept := strings.TrimSuffix(escaped, "…")
+	if utf8.RuneCountInString(escaped) > slackTextLimit || kept == escaped || kept != strings.Repeat("&amp;", len(kept)/len("&amp;")) {
+		t.Errorf("Expected whole escapes cut to %d characters, got %d ending in %q", slackTextLimit, utf8.RuneCountInString(escaped), escaped[max(0, len(escaped)-12):])
+	}
+
+	// A summary too long for the block is cut inside the code fence, which stays closed
+	f, err := newFormatter("slack-attachment", &Config{RepoPath: "/repo/" + strings.Repeat("&", slackTextLimit)}, &runState{})
+	if err != nil {
+		t.Fatalf("Failed to create formatter: %v", err)
+	}
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	stats := &runStats{TopFiles: &topFiles{Largest: []fileSummary{{Path: strings.Repeat("<&>", slackTextLimit)}}}}
+	if err := f.end(w, stats); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &message); err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+	text := message.Blocks[0].Text.Text
+	if utf8.RuneCountInString(text) > slackTextLimit || !strings.HasSuffix(text, "…\n```") || strings.Count(text, "```") != 2 {
+		t.Errorf("Expected a cut summary in a closed fence within %d characters, got %d ending in %q", slackTextLimit, utf8.RuneCountInString(text), text[max(0, len(text)-12):])
+	}
+	if strings.Contains(strings.NewReplacer("&amp;", "", "&lt;", "", "&gt;", "").Replace(text), "&") {
+		t.Errorf("Expected no escape to be split, got %q", text)
+	}
+}
+
+// TestLatexFormat checks that the LaTeX document has balanced environments and a
+// listing per file holding its exact content, even when the content holds the end of
+// a listing or characters outside ASCII
+func TestLatexFormat(t *testing.T) {
+	files := map[string]string{
+		"main.py":         "def main():\n    print(\"100% done\")\n",
+		"pkg/a_b.go":      "package pkg\n",
+		"notes/x.txt":     "plain {text}\n",
+		"notes/utf8.txt":  "café über\n",
+		"tex/listing.tex": "\\begin{lstlisting}\nx = 1\n\\end{lstlisting}\n\\end{document}\n",
+	}
+	repoDir := createFixtureRepo(t, files)
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "latex", Summary: true})
+
+	if !strings.Contains(output, "\\usepackage{listings,xcolor}\n") {
+		t.Errorf("Expected the listings preamble, got:\n%s", output)
+	}
+	// Like LaTeX, skip a listing's body up to the first end of its environment
+	var open []string
+	environment := regexp.MustCompile(`\\(begin|end)\{(\w+)\}`)
+	for rest := output; ; {
+		match := environment.FindStringSubmatchIndex(rest)
+		if match == nil {
+			break
+		}
+		kind, name := rest[match[2]:match[3]], rest[match[4]:match[5]]
+		rest = rest[match[1]:]
+		if kind == "begin" {
+			open = append(open, name)
+			if strings.HasSuffix(name, "listing") || strings.HasPrefix(name, "colligolisting") {
+				end := strings.Index(rest, "\\end{"+name+"}")
+				if end < 0 {
+					t.Fatalf("Listing %s is never closed", name)
+				}
+				rest = rest[end:]
+			}
+			continue
+		}
+		if len(open) == 0 || open[len(open)-1] != name {
+			t.Fatalf("Unbalanced \\end{%s} with open environments %v", name, open)
+		}
+		open = open[:len(open)-1]
+	}
+	if len(open) != 0 {
+		t.Errorf("Expected every environment to be closed, still open: %v", open)
+	}
+	if !strings.HasSuffix(output, "\\end{document}\n") {
+		t.Errorf("Expected the document to end with \\end{document}")
+	}
+
+	listings := map[string]struct{ begin, end string }{
+		"main.py":         {"\\begin{lstlisting}[language=Python]\n", "\\end{lstlisting}\n"},
+		"pkg/a_b.go":      {"\\begin{lstlisting}\n", "\\end{lstlisting}\n"},
+		"notes/x.txt":     {"\\begin{lstlisting}\n", "\\end{lstlisting}\n"},
+		"notes/utf8.txt":  {"\\begin{lstlisting}[literate={é}{{é}}1{ü}{{ü}}1]\n", "\\end{lstlisting}\n"},
+		"tex/listing.tex": {"\\lstnewenvironment{colligolisting}[1][]{\\lstset{#1}}{}\n\\begin{colligolisting}\n", "\\end{colligolisting}\n"},
+	}
+	for name, listing := range listings {
+		heading := "\\subsection{" + latexEscape(name) + "}\n" + listing.begin
+		start := strings.Index(output, heading)
+		if start < 0 {
+			t.Errorf("Expected %q in the output, got:\n%s", heading, output)
+			continue
+		}
+		body, _, found := strings.Cut(output[start+len(heading):], listing.end)
+		if !found || body != files[name] {
+			t.Errorf("Expected the listing of %s to hold %q, got %q", name, files[name], body)
+		}
+	}
+	if !strings.Contains(output, "\\subsection{pkg/a\\_b.go}") {
+		t.Errorf("Expected underscores in the path to be escaped")
+	}
+}
@@ -0,0 +1,77 @@
+// File: src/cmd/functions.go
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Matches Python def statements, including async and indented methods
+var pythonDefPattern = regexp.MustCompile(`(?m)^[ \t]*(?:async[ \t]+)?def[ \t]+([A-Za-z_][A-Za-z0-9_]*)`)
+
+// functionList returns the names of the functions a code file defines: top-level
+// functions and methods (as Type.Method) for Go, and every def statement for Python.
+// It returns nil for other files and for Go files that do not parse.
+func functionList(content []byte, relativePath string) []string {
+	switch strings.ToLower(filepath.Ext(relativePath)) {
+	case ".go":
+		return goFunctions(content, relativePath)
+	case ".py":
+		names := []string{}
+		for _, match := range pythonDefPattern.FindAllSubmatch(content, -1) {
+			names = append(names, string(match[1]))
+		}
+		return names
+	}
+	return nil
+}
+
+// goFunctions lists the top-level functions and methods declared in Go source
+func goFunctions(content []byte, relativePath string) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), filepath.Base(relativePath), content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	names := []string{}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			if receiver := receiverTypeName(fn.Recv.List[0].Type); receiver != "" {
+				name = receiver + "." + name
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// receiverTypeName strips pointers and type parameters from a method receiver
+func receiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// functionsLine renders the text of the -emit-function-list header comment for a file
+func functionsLine(functions []string) string {
+	if len(functions) == 0 {
+		return "Functions: (none)"
+	}
+	return "Functions: " + strings.Join(functions, ", ")
+}
@@ -0,0 +1,57 @@
+// File: src/cmd/functions_test.go
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestEmitFunctionList checks the Functions line written for Go and Python files
+func TestEmitFunctionList(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"api.go": "package api\n\n" +
+			"// Open opens\nfunc Open() {}\n\n" +
+			"func Close() error { return nil }\n\n" +
+			"func Read(p []byte) (int, error) { return 0, nil }\n",
+		"tool.py":   "def main():\n    pass\n",
+		"README.md": "# Readme\n",
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, EmitFunctionList: true, Head: 2})
+
+	if !strings.Contains(output, "# BEGIN FILE: api.go\n# Functions: Open, Close, Read\n\n") {
+		t.Errorf("Expected all three functions in the header of api.go, got:\n%s", output)
+	}
+	if !strings.Contains(output, "# BEGIN FILE: tool.py\n# Functions: main\n\n") {
+		t.Errorf("Expected the def in the header of tool.py, got:\n%s", output)
+	}
+	if !strings.Contains(output, "# BEGIN FILE: README.md\n\n") {
+		t.Errorf("Expected no Functions line for README.md, got:\n%s", output)
+	}
+}
+
+// TestFunctionList checks methods, generic receivers and Python defs
+func TestFunctionList(t *testing.T) {
+	cases := []struct {
+		name     string
+		path     string
+		content  string
+		expected []string
+	}{
+		{"Methods", "a.go", "package a\n\ntype T struct{}\ntype L[K any] []K\n\nfunc (T) Value() {}\nfunc (t *T) Ptr() {}\nfunc (l L[K]) Len() int { return 0 }\nfunc init() {}\n",
+			[]string{"T.Value", "T.Ptr", "L.Len", "init"}},
+		{"No Functions", "b.go", "package b\n\nvar X = 1\n", []string{}},
+		{"Invalid Go", "c.go", "package\n", nil},
+		{"Python", "d.py", "import os\n\nasync def fetch():\n    pass\n\nclass A:\n    def run(self):\n        pass\n",
+			[]string{"fetch", "run"}},
+		{"Other Language", "e.js", "function f() {}\n", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := functionList([]byte(c.content), c.path); !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("Expected %#v, got %#v", c.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// File: src/cmd/gitdiff.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// diffStat counts the lines a file gained and lost since the previous commit
+type diffStat struct {
+	Insertions int
+	Deletions  int
+}
+
+// Format of the header fields added by -git-diff-stat. They follow the marker path
+// and come before any other fields.
+const diffStatFieldFormat = " insertions=%d deletions=%d"
+
+// gitDiffStats runs git diff --numstat against HEAD~1 in repoPath and returns the
+// stats of every changed file, keyed by path relative to repoPath. In a repository
+// with a single commit there is no HEAD~1, so the diff is taken against the empty
+// tree and every committed file counts as added.
+func gitDiffStats(ctx context.Context, repoPath string) (map[string]diffStat, error) {
+	base := "HEAD~1"
+	if _, err := runGitCommand(ctx, repoPath, nil, "rev-parse", "--verify", "--quiet", base); err != nil {
+		emptyTree, err := runGitCommand(ctx, repoPath, strings.NewReader(""), "hash-object", "-t", "tree", "--stdin")
+		if err != nil {
+			return nil, err
+		}
+		base = strings.TrimSpace(string(emptyTree))
+	}
+	output, err := runGitCommand(ctx, repoPath, nil, "diff", "--numstat", "-z", "--no-renames", "--relative", base, "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	return parseNumstat(output)
+}
+
+// runGitCommand runs git with args in dir and returns its output, adding git's
+// error message to the error when it fails
+func runGitCommand(ctx context.Context, dir string, stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		name := "git " + strings.Join(args[:min(2, len(args))], " ")
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s: %w: %s", name, err, message)
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return output, nil
+}
+
+// parseNumstat parses the NUL-terminated records of git diff --numstat -z. Binary
+// files, reported as "-", count as no line changes.
+func parseNumstat(output []byte) (map[string]diffStat, error) {
+	stats := make(map[string]diffStat)
+	for _, record := range strings.Split(string(output), "\x00") {
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\t", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git diff --numstat record %q", record)
+		}
+		var stat diffStat
+		if fields[0] != "-" {
+			n, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected git diff --numstat record %q", record)
+			}
+			stat.Insertions = n
+		}
+		if fields[1] != "-" {
+			n, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected git diff --numstat record %q", record)
+			}
+			stat.Deletions = n
+		}
+		stats[filepath.FromSlash(fields[2])] = stat
+	}
+	return stats, nil
+}
+
+// addDiffStats sets the diff stat of every entry; files git reports no changes for
+// get zero counts
+func addDiffStats(entries []fileEntry, stats map[string]diffStat) {
+	for i := range entries {
+		stat := stats[entries[i].RelativePath]
+		entries[i].DiffStat = &stat
+	}
+}
+
+// diffStatField renders the -git-diff-stat header fields for entry, if it has stats
+func diffStatField(entry fileEntry) string {
+	if entry.DiffStat == nil {
+		return ""
+	}
+	return fmt.Sprintf(diffStatFieldFormat, entry.DiffStat.Insertions, entry.DiffStat.Deletions)
+}
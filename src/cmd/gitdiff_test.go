@@ -0,0 +1,120 @@
+// File: src/cmd/gitdiff_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Helper function to run git in dir with a fixed identity, failing the test on error
+func runGit(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Colligo", "-c", "user.email=colligo@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+}
+
+// TestGitDiffStat checks that headers carry the line changes of the last commit
+func TestGitDiffStat(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repoDir := createFixtureRepo(t, map[string]string{
+		"changed.txt":   "one\ntwo\nthree\n",
+		"unchanged.txt": "same\n",
+	})
+	runGit(t, repoDir, "init", "-q")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-q", "-m", "first")
+	if err := os.WriteFile(filepath.Join(repoDir, "changed.txt"), []byte("one\n2\nthree\nfour\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify fixture file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "untracked.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatalf("Failed to write fixture file: %v", err)
+	}
+	runGit(t, repoDir, "add", "changed.txt")
+	runGit(t, repoDir, "commit", "-q", "-m", "second")
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, GitDiffStat: true})
+
+	for _, header := range []string{
+		"# BEGIN FILE: changed.txt insertions=2 deletions=1\n",
+		"# BEGIN FILE: unchanged.txt insertions=0 deletions=0\n",
+		"# BEGIN FILE: untracked.txt insertions=0 deletions=0\n",
+	} {
+		if !strings.Contains(output, header) {
+			t.Errorf("Expected header %q, got:\n%s", header, output)
+		}
+	}
+
+	extracted, err := parseCombinedOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if len(extracted) != 3 || extracted[0].path != "changed.txt" || string(extracted[0].content) != "one\n2\nthree\nfour\n" {
+		t.Errorf("Expected extract to strip the diff stat fields, got %+v", extracted)
+	}
+}
+
+// TestGitDiffStatSingleCommit checks that a repository with one commit, which has no
+// HEAD~1, reports its committed files as added
+func TestGitDiffStatSingleCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repoDir := createFixtureRepo(t, map[string]string{"a.txt": "one\ntwo\n"})
+	runGit(t, repoDir, "init", "-q")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-q", "-m", "first")
+	if err := os.WriteFile(filepath.Join(repoDir, "untracked.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatalf("Failed to write fixture file: %v", err)
+	}
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, GitDiffStat: true})
+
+	for _, header := range []string{
+		"# BEGIN FILE: a.txt insertions=2 deletions=0\n",
+		"# BEGIN FILE: untracked.txt insertions=0 deletions=0\n",
+	} {
+		if !strings.Contains(output, header) {
+			t.Errorf("Expected header %q, got:\n%s", header, output)
+		}
+	}
+}
+
+// TestGitDiffStatNotARepository checks that -git-diff-stat fails outside a git repository
+func TestGitDiffStatNotARepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repoDir := createFixtureRepo(t, map[string]string{"a.txt": "a\n"})
+	// Stop git from finding a repository above the temp directory
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(repoDir))
+
+	cfg := &Config{RepoPath: repoDir, OutputFile: filepath.Join(createTempDir(t, "colligo_output"), "combined.txt"), GitDiffStat: true}
+	if err := run(context.Background(), getLogger(), cfg); err == nil {
+		t.Error("Expected -git-diff-stat to fail outside a git repository")
+	}
+}
+
+// TestParseNumstat checks parsing of text, binary and malformed numstat records
+func TestParseNumstat(t *testing.T) {
+	stats, err := parseNumstat([]byte("3\t1\tdir/a.go\x00-\t-\timage.png\x00"))
+	if err != nil {
+		t.Fatalf("parseNumstat failed: %v", err)
+	}
+	if stats[filepath.FromSlash("dir/a.go")] != (diffStat{Insertions: 3, Deletions: 1}) {
+		t.Errorf("Unexpected stats for dir/a.go: %+v", stats[filepath.FromSlash("dir/a.go")])
+	}
+	if stat, ok := stats["image.png"]; !ok || stat != (diffStat{}) {
+		t.Errorf("Expected zero stats for a binary file, got %+v (found=%v)", stat, ok)
+	}
+	if _, err := parseNumstat([]byte("x\t1\ta.go\x00")); err == nil {
+		t.Error("Expected a malformed record to fail")
+	}
+}
@@ -0,0 +1,34 @@
+// File: src/cmd/hardlinks.go
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+)
+
+// fileID identifies the underlying file of a path, shared by all of its hardlinks
+type fileID struct {
+	Device uint64
+	Inode  uint64
+}
+
+// markHardlinks keeps the first path of each hardlinked file and turns later paths
+// to the same inode into placeholders pointing at it. Symlinks resolve to their
+// target's inode without being hardlinks, so they are left to -prefer-file-over-symlink.
+func markHardlinks(logger *slog.Logger, entries []fileEntry) {
+	seen := make(map[fileID]string)
+	for i := range entries {
+		entry := &entries[i]
+		if !entry.HasID || entry.Symlink || entry.Note != "" {
+			continue
+		}
+		original, ok := seen[entry.ID]
+		if !ok {
+			seen[entry.ID] = entry.RelativePath
+			continue
+		}
+		logger.Debug("Replacing hardlink with placeholder", "file", entry.RelativePath, "original", original)
+		entry.Note = fmt.Sprintf("# HARDLINK TO: %s (inode=%d)", filepath.ToSlash(original), entry.ID.Inode)
+	}
+}
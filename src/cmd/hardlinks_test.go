@@ -0,0 +1,44 @@
+// File: src/cmd/hardlinks_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestPreserveHardlinks checks that only the first of two hardlinks is written in full,
+// and that a symlink to the same file is not taken for a hardlink
+func TestPreserveHardlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Hardlink tracking relies on Unix inode numbers")
+	}
+
+	content := "shared hardlinked content\n"
+	repoDir := createFixtureRepo(t, map[string]string{"a.txt": content})
+	if err := os.Link(filepath.Join(repoDir, "a.txt"), filepath.Join(repoDir, "b.txt")); err != nil {
+		t.Fatalf("Failed to create hardlink: %v", err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(repoDir, "c.txt")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, PreserveHardlinks: true})
+
+	if count := strings.Count(output, content); count != 2 {
+		t.Errorf("Expected full copies for a.txt and the c.txt symlink, got %d", count)
+	}
+	if !strings.Contains(output, "# BEGIN FILE: b.txt\n\n# HARDLINK TO: a.txt (inode=") {
+		t.Errorf("Expected a hardlink placeholder for b.txt, got:\n%s", output)
+	}
+	if strings.Contains(output, "# BEGIN FILE: c.txt\n\n# HARDLINK TO:") {
+		t.Errorf("Expected no hardlink placeholder for the c.txt symlink, got:\n%s", output)
+	}
+
+	output = runAndReadOutput(t, &Config{RepoPath: repoDir})
+	if count := strings.Count(output, content); count != 3 {
+		t.Errorf("Expected every copy without -preserve-hardlinks, got %d", count)
+	}
+}
@@ -0,0 +1,24 @@
+// File: src/cmd/hooks.go
+package main
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// runHook runs a user-supplied shell command in the current working directory,
+// inheriting the environment plus any extra KEY=VALUE pairs
+func runHook(command string, extraEnv ...string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), extraEnv...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,60 @@
+// File: src/cmd/hooks_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestPreRunCmdFailureAborts checks that a failing pre-run command stops the run before any output
+func TestPreRunCmdFailureAborts(t *testing.T) {
+	logger := getLogger()
+	repoDir := createTempDir(t, "colligo_hook_repo")
+	outDir := createTempDir(t, "colligo_hook_out")
+	writeSizedFile(t, filepath.Join(repoDir, "a.txt"), 10)
+
+	cfg := &Config{
+		RepoPath:   repoDir,
+		OutputFile: filepath.Join(outDir, "combined.txt"),
+		PreRunCmd:  "exit 3",
+	}
+	if err := run(context.Background(), logger, cfg); err == nil {
+		t.Fatalf("Expected the run to fail when the pre-run command fails")
+	}
+	if _, err := os.Stat(cfg.OutputFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no output file after an aborted run, got err=%v", err)
+	}
+}
+
+// TestPostRunCmdReceivesOutput checks that the post-run command sees COLLIGO_OUTPUT and cannot fail the run
+func TestPostRunCmdReceivesOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Post-run command fixture uses POSIX shell syntax")
+	}
+	logger := getLogger()
+	repoDir := createTempDir(t, "colligo_hook_repo")
+	outDir := createTempDir(t, "colligo_hook_out")
+	writeSizedFile(t, filepath.Join(repoDir, "a.txt"), 10)
+
+	envFile := filepath.Join(outDir, "env.txt")
+	cfg := &Config{
+		RepoPath:   repoDir,
+		OutputFile: filepath.Join(outDir, "combined.txt"),
+		PostRunCmd: `printf '%s' "$COLLIGO_OUTPUT" > '` + envFile + `'; exit 1`,
+	}
+	if err := run(context.Background(), logger, cfg); err != nil {
+		t.Fatalf("Expected a failing post-run command not to fail the run, got: %v", err)
+	}
+
+	data, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("Post-run command did not run: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != cfg.OutputFile {
+		t.Errorf("Expected COLLIGO_OUTPUT=%s, got %q", cfg.OutputFile, string(data))
+	}
+}
@@ -0,0 +1,13 @@
+// File: src/cmd/inode_other.go
+//go:build !unix
+
+package main
+
+import (
+	"os"
+)
+
+// fileIDOf returns the device and inode of a file, if the platform exposes them
+func fileIDOf(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
@@ -0,0 +1,18 @@
+// File: src/cmd/inode_unix.go
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIDOf returns the device and inode of a file, if the platform exposes them
+func fileIDOf(info os.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{Device: uint64(stat.Dev), Inode: uint64(stat.Ino)}, true
+}
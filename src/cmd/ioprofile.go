@@ -0,0 +1,51 @@
+// File: src/cmd/ioprofile.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ioProfile holds the read-ahead and buffer size defaults for a kind of storage
+type ioProfile struct {
+	readAhead  int
+	bufferSize byteSize
+}
+
+// Profiles selectable with -io-profile. Local disks answer quickly, so files are
+// read when written with small buffers; network filesystems have high latency per
+// request, which reading many files ahead with large buffers hides.
+var ioProfiles = map[string]ioProfile{
+	"local":   {readAhead: 0, bufferSize: defaultBufferSize},
+	"network": {readAhead: 16, bufferSize: 1 << 20},
+}
+
+// ioProfileNames lists the supported I/O profiles
+func ioProfileNames() []string {
+	var names []string
+	for name := range ioProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyIOProfile fills in -read-ahead and -buffer-size from the -io-profile when
+// they are left at their defaults
+func applyIOProfile(cfg *Config) error {
+	if cfg.IOProfile == "" {
+		return nil
+	}
+	profile, ok := ioProfiles[cfg.IOProfile]
+	if !ok {
+		return fmt.Errorf("unknown -io-profile %q (supported: %s)", cfg.IOProfile, strings.Join(ioProfileNames(), ", "))
+	}
+	if cfg.ReadAhead == 0 {
+		cfg.ReadAhead = profile.readAhead
+	}
+	if cfg.BufferSize <= 0 || cfg.BufferSize == defaultBufferSize {
+		cfg.BufferSize = profile.bufferSize
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// File: src/cmd/ioprofile_test.go
+package main
+
+import (
+	"testing"
+)
+
+// TestApplyIOProfile checks the profile defaults and that explicit settings win
+func TestApplyIOProfile(t *testing.T) {
+	cases := []struct {
+		name       string
+		cfg        Config
+		readAhead  int
+		bufferSize byteSize
+	}{
+		{"No Profile", Config{BufferSize: defaultBufferSize}, 0, defaultBufferSize},
+		{"Local", Config{IOProfile: "local", BufferSize: defaultBufferSize}, 0, defaultBufferSize},
+		{"Network", Config{IOProfile: "network", BufferSize: defaultBufferSize}, 16, 1 << 20},
+		{"Network Unset Buffer", Config{IOProfile: "network"}, 16, 1 << 20},
+		{"Explicit Settings", Config{IOProfile: "network", ReadAhead: 2, BufferSize: 64 << 10}, 2, 64 << 10},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := c.cfg
+			if err := applyIOProfile(&cfg); err != nil {
+				t.Fatalf("applyIOProfile failed: %v", err)
+			}
+			if cfg.ReadAhead != c.readAhead || cfg.BufferSize != c.bufferSize {
+				t.Errorf("Expected read-ahead %d and buffer size %d, got %d and %d", c.readAhead, c.bufferSize, cfg.ReadAhead, cfg.BufferSize)
+			}
+		})
+	}
+
+	if err := applyIOProfile(&Config{IOProfile: "tape"}); err == nil {
+		t.Errorf("Expected an unknown profile to be rejected")
+	}
+}
@@ -0,0 +1,64 @@
+// File: src/cmd/languages.go
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// language describes how Colligo treats the source files of one language
+type language struct {
+	Name        string
+	LineComment string
+}
+
+// Languages keyed by lower-case file extension
+var languagesByExtension = map[string]language{
+	".go":    {"go", "//"},
+	".c":     {"c", "//"},
+	".h":     {"c", "//"},
+	".cpp":   {"cpp", "//"},
+	".cc":    {"cpp", "//"},
+	".hpp":   {"cpp", "//"},
+	".cs":    {"csharp", "//"},
+	".java":  {"java", "//"},
+	".kt":    {"kotlin", "//"},
+	".swift": {"swift", "//"},
+	".rs":    {"rust", "//"},
+	".js":    {"javascript", "//"},
+	".jsx":   {"javascript", "//"},
+	".ts":    {"typescript", "//"},
+	".tsx":   {"typescript", "//"},
+	".php":   {"php", "//"},
+	".py":    {"python", "#"},
+	".rb":    {"ruby", "#"},
+	".pl":    {"perl", "#"},
+	".r":     {"r", "#"},
+	".sh":    {"bash", "#"},
+	".bash":  {"bash", "#"},
+	".yaml":  {"yaml", "#"},
+	".yml":   {"yaml", "#"},
+	".toml":  {"toml", "#"},
+	".sql":   {"sql", "--"},
+	".lua":   {"lua", "--"},
+	".md":    {"markdown", ""},
+	".json":  {"json", ""},
+	".html":  {"html", ""},
+	".xml":   {"xml", ""},
+	".css":   {"css", ""},
+}
+
+// Languages keyed by lower-case file name, for files without a telling extension
+var languagesByName = map[string]language{
+	"makefile":   {"makefile", "#"},
+	"dockerfile": {"dockerfile", "#"},
+}
+
+// languageFor returns the language of a file, or an empty language if it is unknown
+func languageFor(path string) language {
+	base := strings.ToLower(filepath.Base(path))
+	if lang, ok := languagesByName[base]; ok {
+		return lang
+	}
+	return languagesByExtension[filepath.Ext(base)]
+}
@@ -0,0 +1,93 @@
+// File: src/cmd/lock.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Default for -lock-timeout
+const defaultLockTimeout = 30 * time.Second
+
+// How often a blocked lock is retried
+const lockPollInterval = 50 * time.Millisecond
+
+// duration is a time.Duration written as a string such as 30s or 2m
+type duration time.Duration
+
+func (d *duration) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *duration) Set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf("invalid duration %q: expected a non-negative value such as 30s or 2m", value)
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// UnmarshalJSON accepts a duration string such as "30s"
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("invalid duration %s: expected a string such as \"30s\"", data)
+	}
+	return d.Set(text)
+}
+
+// createOutputFile opens path for writing and truncates it. With -output-lock it
+// first takes an exclusive advisory lock, held until the file is closed, so that
+// concurrent runs writing the same output take turns instead of interleaving.
+func createOutputFile(ctx context.Context, cfg *Config, path string) (*os.File, error) {
+	if !cfg.OutputLock {
+		return os.Create(path)
+	}
+	// Truncating before the lock is held would clobber another run's output
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(ctx, file, time.Duration(cfg.LockTimeout)); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Truncate(0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// lockFile blocks until it holds an exclusive lock on file, ctx is cancelled or the
+// timeout passes. A timeout of 0 waits indefinitely.
+func lockFile(ctx context.Context, file *os.File, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			return fmt.Errorf("locking %s: %w", file.Name(), err)
+		}
+		if locked {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("locking %s: another process held the lock for longer than -lock-timeout %s", file.Name(), timeout)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// File: src/cmd/lock_other.go
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// tryLockFile reports that output locking is unavailable on this platform
+func tryLockFile(file *os.File) (bool, error) {
+	return false, errors.New("-output-lock is not supported on this platform")
+}
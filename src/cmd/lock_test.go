@@ -0,0 +1,87 @@
+// File: src/cmd/lock_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLockFileWaits checks that a second locker waits until the first releases the lock
+func TestLockFileWaits(t *testing.T) {
+	path := filepath.Join(createTempDir(t, "colligo_lock"), "combined.txt")
+	const hold = 300 * time.Millisecond
+
+	locked := make(chan struct{})
+	released := make(chan time.Time, 1)
+	go func() {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			t.Errorf("First open failed: %v", err)
+			close(locked)
+			return
+		}
+		if err := lockFile(context.Background(), file, time.Second); err != nil {
+			t.Errorf("First lock failed: %v", err)
+		}
+		close(locked)
+		time.Sleep(hold)
+		released <- time.Now()
+		file.Close()
+	}()
+
+	<-locked
+	acquired := make(chan time.Time, 1)
+	go func() {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			t.Errorf("Second open failed: %v", err)
+			acquired <- time.Time{}
+			return
+		}
+		defer file.Close()
+		if err := lockFile(context.Background(), file, 5*time.Second); err != nil {
+			t.Errorf("Second lock failed: %v", err)
+		}
+		acquired <- time.Now()
+	}()
+
+	releasedAt, acquiredAt := <-released, <-acquired
+	if acquiredAt.Before(releasedAt) {
+		t.Errorf("Expected the second lock to wait for the first, acquired %v before release", releasedAt.Sub(acquiredAt))
+	}
+}
+
+// TestLockFileTimeout checks that -lock-timeout gives up on a lock that stays held
+func TestLockFileTimeout(t *testing.T) {
+	path := filepath.Join(createTempDir(t, "colligo_lock"), "combined.txt")
+	holder, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open lock file: %v", err)
+	}
+	defer holder.Close()
+	if err := lockFile(context.Background(), holder, time.Second); err != nil {
+		t.Fatalf("Failed to take the lock: %v", err)
+	}
+
+	cfg := &Config{OutputFile: path, OutputLock: true, LockTimeout: duration(150 * time.Millisecond)}
+	if _, err := openSinks(context.Background(), cfg, &runState{}); err == nil || !strings.Contains(err.Error(), "-lock-timeout") {
+		t.Errorf("Expected a lock timeout error, got %v", err)
+	}
+}
+
+// TestDuration checks parsing of duration flag and config values
+func TestDuration(t *testing.T) {
+	var d duration
+	if err := d.UnmarshalJSON([]byte(`"2m"`)); err != nil || time.Duration(d) != 2*time.Minute {
+		t.Errorf("Expected 2m, got %v (err %v)", time.Duration(d), err)
+	}
+	for _, invalid := range []string{`"soon"`, `"-1s"`, `30`} {
+		if err := d.UnmarshalJSON([]byte(invalid)); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+}
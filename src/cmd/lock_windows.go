@@ -0,0 +1,39 @@
+// File: src/cmd/lock_windows.go
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+// tryLockFile takes an exclusive LockFileEx lock on the first byte of file without
+// blocking, reporting whether it was acquired
+func tryLockFile(file *os.File) (bool, error) {
+	var overlapped syscall.Overlapped
+	result, _, err := procLockFileEx.Call(
+		file.Fd(),
+		lockfileExclusiveLock|lockfileFailImmediately,
+		0,
+		1,
+		0,
+		uintptr(unsafe.Pointer(&overlapped)),
+	)
+	if result != 0 {
+		return true, nil
+	}
+	if err == errorLockViolation {
+		return false, nil
+	}
+	return false, err
+}
@@ -2,33 +2,129 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
 )
 
+// fileEntry describes a file selected by the walk, before anything is written
+type fileEntry struct {
+	Path         string
+	RelativePath string
+	Size         int64
+	ModTime      time.Time
+
+	// Per-file transforms, from the global flags or a manifest
+	Head             int
+	StripComments    bool
+	TokenLimit       int
+	TruncateStrategy string
+	CollapseArrays   int
+	GoDocSummary     bool
+	RewriteMDLinks   bool
+	// RepoURL is where rewritten Markdown links point when set
+	RepoURL        string
+	TransformOrder []string
+	// SanitizeUTF8 fixes invalid UTF-8 after all other transforms
+	SanitizeUTF8 string
+
+	// ID identifies the underlying file when HasID is set
+	ID    fileID
+	HasID bool
+	// DiffStat holds the changes since the previous commit with -git-diff-stat
+	DiffStat *diffStat
+	// ChangeCount is the number of commits that touched the file with
+	// -emit-change-frequency
+	ChangeCount *int
+	// FunctionList asks for the names of the functions the file defines, which
+	// are stored in Functions while writing
+	FunctionList bool
+	Functions    []string
+	// Restricted is checked against the content as it is written with
+	// -exclude-if-contains
+	Restricted *restriction
+	// DocSummary is the -emit-go-doc-summaries line, computed from the file as read
+	DocSummary string
+	// Transformed is set while writing when the content differs from the file on
+	// disk, not counting the doc summary line
+	Transformed bool
+	// Symlink is set when the walked path is a symbolic link; Path is then its target
+	Symlink bool
+
+	// Note replaces the file content with a placeholder when set
+	Note string
+}
+
+// tokens estimates the tokens the entry contributes to the output
+func (e fileEntry) tokens() int {
+	if e.Note != "" {
+		return estimateTokens(int64(len(e.Note)))
+	}
+	return estimateTokens(e.Size)
+}
+
+// runState holds what a run works out from the settings and the selected files, so
+// Config keeps only what the user asked for
+type runState struct {
+	// delimiterCollision is the -delimiter-collision mode in effect; -rag-mode
+	// escapes colliding lines unless the user chose to fail or pick a token
+	delimiterCollision string
+	// delimiterToken makes the txt markers unique; it is chosen by
+	// -delimiter-collision auto
+	delimiterToken string
+	// repoStats is computed from the selected files for -repo-stats-header
+	repoStats *repoStats
+	// dependencyGraph is built from the selected files for -emit-dependency-graph
+	dependencyGraph *dependencyGraph
+	// prompt is loaded from -prompt-template before the files are read
+	prompt *promptTemplate
+}
+
 func main() {
-	// Define command-line flags with default values
-	repoPath := flag.String("repo", ".", "Path to your local repository")
-	outputFile := flag.String("output", "", "Output file name (optional)")
-	logLevel := flag.String("log-level", "info", "Set the logging level (debug, info, warn, error)")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate-config":
+			os.Exit(runValidateConfig(os.Args[2:]))
+		case "extract":
+			os.Exit(runExtract(os.Args[2:]))
+		}
+	}
+
+	// Settings from a config file become the defaults that command-line flags override
+	cfg := defaultConfig()
+	if path := findConfigArg(os.Args[1:]); path != "" {
+		if err := loadConfigFile(path, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid config file %s:\n%v\n", path, err)
+			os.Exit(2)
+		}
+	}
+	registerFlags(flag.CommandLine, cfg)
 	flag.Parse()
 
 	// Set the default output file name if not provided
-	if *outputFile == "" {
-		*outputFile = fmt.Sprintf("combined_repo_%s_%s.txt", runtime.GOOS, time.Now().Format("20060102T150405"))
+	if cfg.OutputFile == "" {
+		extension, ok := formatExtensions[cfg.Format]
+		if !ok {
+			extension = ".txt"
+		}
+		cfg.OutputFile = fmt.Sprintf("combined_repo_%s_%s%s", runtime.GOOS, time.Now().Format("20060102T150405"), extension)
 	}
 
 	// Configure logger based on log level
 	var level slog.Level
-	switch strings.ToLower(*logLevel) {
+	switch strings.ToLower(cfg.LogLevel) {
 	case "debug":
 		level = slog.LevelDebug
 	case "info":
@@ -43,44 +139,378 @@ func main() {
 
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
 
-	logger.Info("Starting Colligo", "repoPath", *repoPath, "outputFile", *outputFile)
+	// Stop cleanly on the first interrupt so a partial result is kept; a second one
+	// falls back to the default behaviour and terminates immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := run(ctx, logger, cfg); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(130)
+		}
+		os.Exit(1)
+	}
+}
+
+// run walks the repository and writes the combined output described by cfg
+func run(ctx context.Context, logger *slog.Logger, cfg *Config) error {
+	if cfg.AutoRoot {
+		if root, ok := findRepoRoot(cfg.RepoPath); ok {
+			logger.Info("Detected repository root", "root", root)
+			cfg.RepoPath = root
+		} else {
+			logger.Warn("No repository root marker found, using repository path as given", "repoPath", cfg.RepoPath)
+		}
+	}
+
+	logger.Info("Starting Colligo", "repoPath", cfg.RepoPath, "outputFile", cfg.OutputFile)
 
 	// Normalize repo path
-	normalizedRepoPath, err := filepath.Abs(filepath.Clean(*repoPath))
+	normalizedRepoPath, err := filepath.Abs(filepath.Clean(cfg.RepoPath))
 	if err != nil {
-		logger.Error("Failed to normalize repository path", "repoPath", *repoPath, "error", err)
-		os.Exit(1)
+		logger.Error("Failed to normalize repository path", "repoPath", cfg.RepoPath, "error", err)
+		return err
+	}
+	cfg.RepoPath = normalizedRepoPath
+
+	if cfg.PerFileTruncateStrategy != "" && !validTruncateStrategy(cfg.PerFileTruncateStrategy) {
+		err := fmt.Errorf("unknown truncate strategy %q (supported: %s)", cfg.PerFileTruncateStrategy, strings.Join(truncateStrategies, ", "))
+		logger.Error("Invalid truncate strategy", "error", err)
+		return err
+	}
+
+	if cfg.SanitizeUTF8 != "" && !slices.Contains(sanitizeUTF8Modes, cfg.SanitizeUTF8) {
+		err := fmt.Errorf("unknown -sanitize-utf8 mode %q (supported: %s)", cfg.SanitizeUTF8, strings.Join(sanitizeUTF8Modes, ", "))
+		logger.Error("Invalid UTF-8 sanitizing mode", "error", err)
+		return err
+	}
+
+	if err := applyIOProfile(cfg); err != nil {
+		logger.Error("Invalid I/O profile", "error", err)
+		return err
+	}
+	if cfg.IOProfile != "" {
+		logger.Debug("Using I/O profile", "profile", cfg.IOProfile, "readAhead", cfg.ReadAhead, "bufferSize", int64(cfg.BufferSize))
+	}
+
+	if _, err := newTransformChain(cfg.TransformOrder); err != nil {
+		logger.Error("Invalid transform order", "error", err)
+		return err
+	}
+
+	if err := validateDelimiterCollision(cfg.DelimiterCollision); err != nil {
+		logger.Error("Invalid delimiter collision mode", "error", err)
+		return err
+	}
+	if err := validateHeaderCommentStyle(cfg.HeaderCommentStyle); err != nil {
+		logger.Error("Invalid header comment style", "error", err)
+		return err
+	}
+	if err := validateSortOrder(cfg.Sort); err != nil {
+		logger.Error("Invalid sort order", "error", err)
+		return err
+	}
+	// RAG output must stay parseable, so colliding lines are escaped unless the
+	// user chose to fail or pick a token instead
+	state := &runState{delimiterCollision: cfg.DelimiterCollision}
+	if cfg.RAGMode && (cfg.DelimiterCollision == "" || cfg.DelimiterCollision == "warn") {
+		state.delimiterCollision = "escape"
+	}
+
+	if err := validateFormats(cfg); err != nil {
+		logger.Error("Invalid output format", "error", err)
+		return err
+	}
+	if cfg.PromptTemplate != "" {
+		if state.prompt, err = loadPromptTemplate(cfg.PromptTemplate); err != nil {
+			logger.Error("Invalid prompt template", "promptTemplate", cfg.PromptTemplate, "error", err)
+			return err
+		}
+		for _, target := range outputTargets(cfg) {
+			if !promptFormats[target.format] {
+				logger.Warn("Skipping the prompt template, which would make this format invalid", "format", target.format, "outputFile", target.path)
+			}
+		}
+	}
+
+	if cfg.PreRunCmd != "" {
+		logger.Info("Running pre-run command", "command", cfg.PreRunCmd)
+		if err := runHook(cfg.PreRunCmd); err != nil {
+			logger.Error("Pre-run command failed", "command", cfg.PreRunCmd, "error", err)
+			return err
+		}
+	}
+
+	var entries []fileEntry
+	if cfg.Manifest != "" {
+		entries, err = manifestFiles(logger, cfg)
+		if err != nil {
+			logger.Error("Error reading manifest", "manifest", cfg.Manifest, "error", err)
+			return err
+		}
+	} else {
+		entries, err = collectFiles(ctx, logger, cfg)
+		if err != nil {
+			logger.Error("Error walking the path", "repoPath", cfg.RepoPath, "error", err)
+			return err
+		}
+		if cfg.ExcludeMigrations {
+			entries = excludeMigrations(logger, entries, cfg.KeepLatestMigrations, cfg.ForceInclude)
+		}
+	}
+
+	if cfg.PreserveHardlinks {
+		markHardlinks(logger, entries)
+	}
+	if cfg.PreferFileOverSymlink {
+		markSymlinks(logger, entries)
+	}
+	if len(cfg.ExcludeIfContains) > 0 {
+		markRestricted(logger, entries, cfg.ExcludeIfContains, cfg.IgnoreCase)
+	}
+
+	if cfg.GitDiffStat {
+		stats, err := gitDiffStats(ctx, cfg.RepoPath)
+		if err != nil {
+			logger.Error("Error reading git diff stats", "repoPath", cfg.RepoPath, "error", err)
+			return err
+		}
+		addDiffStats(entries, stats)
+	}
+	// Sorting by change frequency needs the counts even if they are not emitted
+	if cfg.EmitChangeFrequency || cfg.Sort == "change-frequency-desc" {
+		counts, err := gitChangeCounts(ctx, cfg.RepoPath)
+		if err != nil {
+			logger.Error("Error reading git change counts", "repoPath", cfg.RepoPath, "error", err)
+			return err
+		}
+		if cfg.EmitChangeFrequency {
+			addChangeCounts(entries, counts)
+		}
+		if cfg.Sort == "change-frequency-desc" {
+			sortByChangeFrequency(entries, counts)
+		}
+	}
+
+	if cfg.WarnFileTokens > 0 {
+		warnLargeFiles(logger, entries, cfg.WarnFileTokens)
+	}
+
+	if cfg.MaxTokens > 0 {
+		if cfg.FitBudget {
+			entries = fitBudget(logger, entries, cfg.MaxTokens)
+		} else {
+			entries = truncateToBudget(logger, entries, cfg.MaxTokens)
+		}
+	}
+
+	var seen *seenStore
+	if cfg.SeenStore != "" {
+		seen, err = loadSeenStore(cfg.SeenStore)
+		if err != nil {
+			logger.Error("Error reading seen store", "seenStore", cfg.SeenStore, "error", err)
+			return err
+		}
+		seen.mark(logger, entries)
+	}
+
+	if cfg.EmitDependencyGraph {
+		state.dependencyGraph = buildDependencyGraph(logger, cfg.RepoPath, entries)
+	}
+
+	if cfg.RepoStatsHeader {
+		state.repoStats = computeRepoStats(cfg.RepoPath, entries)
 	}
-	*repoPath = normalizedRepoPath
 
-	// Open the output file for writing
-	outFile, err := os.Create(*outputFile)
+	if err := resolveDelimiterCollisions(logger, cfg, state, entries); err != nil {
+		logger.Error("Delimiter collision check failed", "error", err)
+		return err
+	}
+
+	stats, err := writeOutput(ctx, logger, cfg, state, entries)
+	if err != nil {
+		return err
+	}
+
+	if seen != nil {
+		if err := seen.save(); err != nil {
+			logger.Error("Error writing seen store", "seenStore", cfg.SeenStore, "error", err)
+			return err
+		}
+	}
+
+	if cfg.StatsFile != "" {
+		if err := writeStatsFile(cfg.StatsFile, stats); err != nil {
+			logger.Error("Error writing stats file", "statsFile", cfg.StatsFile, "error", err)
+			return err
+		}
+	}
+
+	logger.Info("Successfully combined files", "outputFile", cfg.OutputFile, "files", stats.Files)
+
+	// A failing post-run command is reported but does not fail the run
+	if cfg.PostRunCmd != "" {
+		outputPath := outputTargets(cfg)[0].path
+		if abs, err := filepath.Abs(outputPath); err == nil {
+			outputPath = abs
+		}
+		logger.Info("Running post-run command", "command", cfg.PostRunCmd)
+		if err := runHook(cfg.PostRunCmd, "COLLIGO_OUTPUT="+outputPath); err != nil {
+			logger.Error("Post-run command failed", "command", cfg.PostRunCmd, "error", err)
+		}
+	}
+	return nil
+}
+
+// writeOutput writes the combined output for entries to every output sink and closes them
+func writeOutput(ctx context.Context, logger *slog.Logger, cfg *Config, state *runState, entries []fileEntry) (*runStats, error) {
+	// Open the output files for writing
+	sinks, err := openSinks(ctx, cfg, state)
 	if err != nil {
 		logger.Error("Error creating output file", "error", err)
-		os.Exit(1)
+		return nil, err
 	}
-	defer func() {
-		if err := outFile.Close(); err != nil {
-			logger.Error("Error closing output file", "error", err)
+	defer closeSinks(logger, sinks)
+
+	for _, sink := range sinks {
+		if err := sink.format.begin(sink.writer); err != nil {
+			logger.Error("Error writing output preamble", "outputFile", sink.path, "error", err)
+			return nil, err
 		}
-	}()
+	}
 
-	writer := bufio.NewWriter(outFile)
+	var reader contentReader = newFileReader(cfg)
+	if cfg.ReadAhead > 0 {
+		prefetch := newPrefetchReader(ctx, reader, entries, cfg.ReadAhead)
+		defer prefetch.stop()
+		reader = prefetch
+	}
+	stats := &runStats{}
+	for _, entry := range entries {
+		// On interrupt, keep what has been written so far instead of losing the buffer
+		if ctx.Err() != nil {
+			for _, sink := range sinks {
+				if err := sink.format.end(sink.writer, nil); err != nil {
+					logger.Error("Error writing output epilogue", "outputFile", sink.path, "error", err)
+				}
+			}
+			if err := flushSinks(sinks); err != nil {
+				logger.Error("Error flushing writer", "error", err)
+				return nil, err
+			}
+			logger.Warn("Interrupted, partial result written", "outputFile", cfg.OutputFile,
+				"filesCompleted", stats.Files, "filesTotal", len(entries))
+			return nil, ctx.Err()
+		}
+
+		// Write the file content to the output files
+		content, err := writeFileContent(logger, sinks, reader, entry)
+		if err != nil {
+			logger.Error("Error processing file", "file", entry.Path, "error", err)
+			continue
+		}
+		if entry.Note == "" {
+			stats.add(entry, content)
+		}
+	}
+
+	if cfg.SizeHistogram {
+		stats.SizeHistogram = buildSizeHistogram(stats.sizes)
+	}
+	if cfg.SummaryTopN > 0 {
+		stats.TopFiles = buildTopFiles(stats.files, cfg.SummaryTopN)
+	}
+
+	var summary *runStats
+	if cfg.Summary {
+		summary = stats
+	}
+	for _, sink := range sinks {
+		if err := sink.format.end(sink.writer, summary); err != nil {
+			logger.Error("Error writing output epilogue", "outputFile", sink.path, "error", err)
+			return nil, err
+		}
+		if !cfg.OutputChecksum {
+			continue
+		}
+		if sink.checksum == nil {
+			logger.Warn("Skipping the checksum trailer, which would make this format invalid", "outputFile", sink.path)
+			continue
+		}
+		if err := writeChecksumTrailer(sink); err != nil {
+			logger.Error("Error writing output checksum", "outputFile", sink.path, "error", err)
+			return nil, err
+		}
+	}
+
+	// Flush the buffers to ensure all content is written
+	if err = flushSinks(sinks); err != nil {
+		logger.Error("Error flushing writer", "error", err)
+		return nil, err
+	}
+
+	if cfg.VerifyOutput {
+		if err := verifySinks(sinks, func(path string) (io.ReadCloser, error) { return os.Open(path) }); err != nil {
+			logger.Error("Output verification failed", "error", err)
+			return nil, err
+		}
+		logger.Info("Verified output against the written bytes", "outputs", len(sinks))
+	}
+	return stats, nil
+}
 
-	// Walk through the repository directory
-	err = filepath.WalkDir(*repoPath, func(path string, d os.DirEntry, err error) error {
+// collectFiles walks the repository and returns the files to include, in walk order
+func collectFiles(ctx context.Context, logger *slog.Logger, cfg *Config) ([]fileEntry, error) {
+	// The output files may live inside the repository, so they must never be collected
+	outputPaths := make(map[string]bool)
+	for _, target := range outputTargets(cfg) {
+		outputPath, err := filepath.Abs(target.path)
+		if err != nil {
+			logger.Error("Failed to normalize output path", "outputFile", target.path, "error", err)
+			return nil, err
+		}
+		outputPaths[outputPath] = true
+	}
+
+	filter, err := newInclusionFilter(cfg)
+	if err != nil {
+		logger.Error("Failed to prepare inclusion rules", "error", err)
+		return nil, err
+	}
+
+	var entries []fileEntry
+	visited := 0
+	err = filepath.WalkDir(cfg.RepoPath, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			logger.Error("Error accessing path", "path", path, "error", err)
 			return err
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Guard against walking far more than intended, such as a mistyped -repo /
+		visited++
+		if cfg.MaxWalkEntries > 0 && visited > cfg.MaxWalkEntries {
+			return fmt.Errorf("walk stopped after visiting %d entries under %s; check that -repo points at the project you meant, or raise -max-walk-entries (0 disables the limit)",
+				cfg.MaxWalkEntries, cfg.RepoPath)
+		}
 
 		// Get the relative path
-		relativePath, err := filepath.Rel(*repoPath, path)
+		relativePath, err := filepath.Rel(cfg.RepoPath, path)
 		if err != nil {
-			logger.Error("Error getting relative path", "base", *repoPath, "target", path, "error", err)
+			logger.Error("Error getting relative path", "base", cfg.RepoPath, "target", path, "error", err)
 			return err
 		}
 
+		// Skip the output files if they're within the repo directory
+		if outputPaths[path] {
+			return nil
+		}
+
 		// Normalize and evaluate symbolic links
 		evaluatedPath, err := filepath.EvalSymlinks(path)
 		if err != nil {
@@ -95,44 +525,61 @@ func main() {
 		}
 		path = normalizedPath
 
-		// Skip the output file if it's within the repo directory
-		if relativePath == *outputFile {
-			return nil
-		}
-
-		// Exclude hidden files and directories, but include .github
-		if d.IsDir() {
-			if isHidden(d.Name()) && d.Name() != ".github" {
+		// Apply the exclusion rules, letting force-included paths override them
+		if reason := filter.excludeReason(relativePath, d); reason != "" {
+			if d.IsDir() {
+				if filter.descendExcluded(relativePath) {
+					return nil
+				}
+				logger.Debug("Skipping directory", "path", relativePath, "reason", reason)
 				return filepath.SkipDir
 			}
-			return nil
-		} else {
-			if isHidden(d.Name()) {
+			if !filter.forced(relativePath) {
+				logger.Debug("Skipping file", "path", relativePath, "reason", reason)
 				return nil
 			}
+			logger.Debug("Force-including file", "path", relativePath, "reason", reason)
+		}
+		if d.IsDir() {
+			return nil
 		}
 
-		// Write the file content to the output file
-		err = writeFileContent(logger, writer, path, relativePath)
+		info, err := os.Stat(path)
 		if err != nil {
-			logger.Error("Error processing file", "file", path, "error", err)
+			logger.Error("Error reading file info", "file", path, "error", err)
+			return nil
 		}
 
+		entry := newFileEntry(cfg, path, relativePath, info)
+		entry.Symlink = d.Type()&os.ModeSymlink != 0
+		entries = append(entries, entry)
 		return nil
 	})
+	return entries, err
+}
 
-	if err != nil {
-		logger.Error("Error walking the path", "repoPath", *repoPath, "error", err)
-		os.Exit(1)
-	}
-
-	// Flush the buffer to ensure all content is written
-	if err = writer.Flush(); err != nil {
-		logger.Error("Error flushing writer", "error", err)
-		os.Exit(1)
+// newFileEntry builds the entry for a file using the global transform settings
+func newFileEntry(cfg *Config, path string, relativePath string, info os.FileInfo) fileEntry {
+	id, hasID := fileIDOf(info)
+	return fileEntry{
+		Path:             path,
+		RelativePath:     relativePath,
+		Size:             info.Size(),
+		ModTime:          info.ModTime(),
+		Head:             cfg.Head,
+		StripComments:    cfg.StripComments,
+		TokenLimit:       cfg.PerFileTokenLimit,
+		CollapseArrays:   cfg.CollapseArrays,
+		TruncateStrategy: cfg.PerFileTruncateStrategy,
+		GoDocSummary:     cfg.EmitGoDocSummaries,
+		FunctionList:     cfg.EmitFunctionList,
+		RewriteMDLinks:   cfg.RewriteMDLinks,
+		RepoURL:          cfg.RepoURL,
+		TransformOrder:   cfg.TransformOrder,
+		SanitizeUTF8:     cfg.SanitizeUTF8,
+		ID:               id,
+		HasID:            hasID,
 	}
-
-	logger.Info("Successfully combined files", "outputFile", *outputFile)
 }
 
 // Helper function to determine if a file or directory is hidden
@@ -140,44 +587,83 @@ func isHidden(name string) bool {
 	return strings.HasPrefix(name, ".")
 }
 
-// Helper function to write the content of a file to the writer
-func writeFileContent(logger *slog.Logger, writer *bufio.Writer, filePath string, relativePath string) error {
-	// Write the header
-	_, err := writer.WriteString(fmt.Sprintf("\n\n# BEGIN FILE: %s\n\n", relativePath))
-	if err != nil {
-		logger.Error("Error writing header", "file", relativePath, "error", err)
-		return err
+// Helper function to write the content of a file to every output sink. The file is
+// read and transformed once, whatever the number of formats, and the written
+// content is returned for the run statistics.
+func writeFileContent(logger *slog.Logger, sinks []*outputSink, reader contentReader, entry fileEntry) ([]byte, error) {
+	// Placeholders are written without reading the file
+	if entry.Note != "" {
+		return writeNote(logger, sinks, entry)
 	}
 
-	// Open the file for reading
-	file, err := os.Open(filePath)
+	// Read the file so transforms can work on the whole content
+	content, err := reader.read(entry)
 	if err != nil {
-		logger.Error("Error opening file", "file", filePath, "error", err)
-		// Write error message to the output file
-		_, writeErr := writer.WriteString(fmt.Sprintf("# Error reading %s: %v\n", relativePath, err))
-		if writeErr != nil {
-			logger.Error("Error writing error message to output", "file", relativePath, "error", writeErr)
-			return writeErr
+		logger.Error("Error opening file", "file", entry.Path, "error", err)
+		// Write error message to the output files
+		for _, sink := range sinks {
+			if writeErr := sink.format.writeError(sink.writer, entry, err); writeErr != nil {
+				logger.Error("Error writing error message to output", "file", entry.RelativePath, "outputFile", sink.path, "error", writeErr)
+				return nil, writeErr
+			}
 		}
-		return err
+		return nil, err
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			logger.Error("Error closing input file", "file", filePath, "error", err)
-		}
-	}()
 
-	// Copy the file content to the writer
-	_, err = io.Copy(writer, file)
-	if err != nil {
-		logger.Error("Error copying file content", "file", filePath, "error", err)
-		return err
+	// Write the transformed file content
+	original := content
+	// Functions are listed from the file as written on disk, since transforms can
+	// leave code that no longer parses
+	if entry.FunctionList {
+		entry.Functions = functionList(original, entry.RelativePath)
+	}
+	// Text transforms and sanitizing would corrupt images and other binary files, so
+	// those are written as read
+	binary := !strings.HasPrefix(contentMIMEType(entry.RelativePath, original), "text/")
+	if !binary {
+		entry.DocSummary = docSummary(original, entry)
+		content = applyTransforms(content, entry)
+	}
+	// Sanitizing comes last, since truncation can split a multi-byte character
+	if entry.SanitizeUTF8 != "" && !binary {
+		var fixed int
+		if content, fixed = sanitizeUTF8(content, entry.SanitizeUTF8 == "strip"); fixed > 0 {
+			logger.Info("Fixed invalid UTF-8", "file", entry.RelativePath, "sequences", fixed, "mode", entry.SanitizeUTF8)
+		}
+	}
+	// The restricted strings were checked before the run, but the file may have
+	// changed since; check the bytes that would be written
+	if entry.Restricted != nil && (entry.Restricted.matches(original) || entry.Restricted.matches(content)) {
+		logger.Info("Excluding file containing a restricted string", "file", entry.RelativePath)
+		entry.Note = restrictedNote(entry.RelativePath)
+		return writeNote(logger, sinks, entry)
+	}
+	_, rest := splitDocSummary(content, entry)
+	entry.Transformed = !bytes.Equal(original, rest)
+	for _, sink := range sinks {
+		if checker, ok := sink.format.(collisionChecker); ok && checker.collides(content) {
+			logger.Warn("File content contains output delimiters; use -delimiter-collision escape or auto for parseable output",
+				"file", entry.RelativePath, "outputFile", sink.path)
+		}
+	}
+	for _, sink := range sinks {
+		if err := sink.format.writeFile(sink.writer, entry, content); err != nil {
+			logger.Error("Error writing file content", "file", entry.RelativePath, "outputFile", sink.path, "error", err)
+			return nil, err
+		}
 	}
+	return content, nil
+}
 
-	// Write the footer
-	_, err = writer.WriteString(fmt.Sprintf("\n\n# END FILE: %s\n\n", relativePath))
-	if err != nil {
-		logger.Error("Error writing footer", "file", relativePath, "error", err)
+// writeNote writes the placeholder of an entry in place of its content
+func writeNote(logger *slog.Logger, sinks []*outputSink, entry fileEntry) ([]byte, error) {
+	note := []byte(entry.Note + "\n")
+	entry.Transformed = true
+	for _, sink := range sinks {
+		if err := sink.format.writeFile(sink.writer, entry, note); err != nil {
+			logger.Error("Error writing placeholder", "file", entry.RelativePath, "outputFile", sink.path, "error", err)
+			return nil, err
+		}
 	}
-	return err
+	return note, nil
 }
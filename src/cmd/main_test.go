@@ -3,9 +3,13 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -26,6 +30,36 @@ func createTempDir(t *testing.T, prefix string) string {
 	return tmpDir
 }
 
+// Helper function to create a fixture repository from relative paths and contents
+func createFixtureRepo(t *testing.T, files map[string]string) string {
+	repoDir := createTempDir(t, "colligo_fixture_repo")
+	for relativePath, content := range files {
+		path := filepath.Join(repoDir, filepath.FromSlash(relativePath))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create fixture directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write fixture file: %v", err)
+		}
+	}
+	return repoDir
+}
+
+// Helper function to run Colligo with cfg and return the combined output
+func runAndReadOutput(t *testing.T, cfg *Config) string {
+	if cfg.OutputFile == "" {
+		cfg.OutputFile = filepath.Join(createTempDir(t, "colligo_output"), "combined.out")
+	}
+	if err := run(context.Background(), getLogger(), cfg); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	output, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	return string(output)
+}
+
 // TestIsHidden checks the isHidden function for correctness
 func TestIsHidden(t *testing.T) {
 	logger := getLogger()
@@ -74,7 +108,8 @@ func TestWriteFileContent(t *testing.T) {
 	defer outFile.Close()
 
 	writer := bufio.NewWriter(outFile)
-	err = writeFileContent(logger, writer, testFilePath, "test.txt")
+	sinks := []*outputSink{{path: outputPath, file: outFile, writer: writer, format: &txtFormatter{}}}
+	_, err = writeFileContent(logger, sinks, &fileReader{}, fileEntry{Path: testFilePath, RelativePath: "test.txt"})
 	if err != nil {
 		logger.Error("Error writing file content", "file", testFilePath, "error", err)
 		t.Errorf("Error writing file content: %v", err)
@@ -149,3 +184,93 @@ func TestSymbolicLinkResolution(t *testing.T) {
 		logger.Info("Symlink resolved correctly", "link", symlinkPath, "resolvedTo", normalizedResolvedPath)
 	}
 }
+
+// cancelAfterContext reports itself cancelled once Err has been called more than
+// checks times, so a test can interrupt writeOutput between two files
+type cancelAfterContext struct {
+	context.Context
+	checks int
+}
+
+func (c *cancelAfterContext) Err() error {
+	if c.checks == 0 {
+		return context.Canceled
+	}
+	c.checks--
+	return nil
+}
+
+// TestWriteOutputInterrupted checks that an interrupted run leaves flushed outputs
+// holding the files completed before the interrupt, each closed by its epilogue
+func TestWriteOutputInterrupted(t *testing.T) {
+	logger := getLogger()
+	tmpDir := createTempDir(t, "colligo_interrupt_test")
+
+	var entries []fileEntry
+	for _, name := range []string{"first.txt", "second.txt"} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte("content of "+name+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write temp test file: %v", err)
+		}
+		entries = append(entries, fileEntry{Path: path, RelativePath: name})
+	}
+
+	// The first file is written, then the interrupt is seen before the second
+	ctx := &cancelAfterContext{Context: context.Background(), checks: 1}
+	cfg := &Config{OutputFile: filepath.Join(tmpDir, "output.txt"), Formats: []string{"txt", "json"}}
+	if _, err := writeOutput(ctx, logger, cfg, &runState{}, entries); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	data, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatalf("Expected the partial output file to exist: %v", err)
+	}
+	output := string(data)
+	if !strings.Contains(output, "# BEGIN FILE: first.txt\n\ncontent of first.txt\n\n\n# END FILE: first.txt\n") {
+		t.Errorf("Expected the completed file block in the partial output, got:\n%s", output)
+	}
+	if strings.Contains(output, "second.txt") {
+		t.Errorf("Expected nothing of the file after the interrupt, got:\n%s", output)
+	}
+
+	data, err = os.ReadFile(filepath.Join(tmpDir, "output.json"))
+	if err != nil {
+		t.Fatalf("Expected the partial JSON output to exist: %v", err)
+	}
+	var document struct {
+		Files []struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("Expected the JSON epilogue to close the partial output: %v\n%s", err, data)
+	}
+	if len(document.Files) != 1 || document.Files[0].Path != "first.txt" || document.Files[0].Content != "content of first.txt\n" {
+		t.Errorf("Expected only the completed file in the partial JSON output, got %+v", document.Files)
+	}
+}
+
+// TestMaxWalkEntries checks that the walk aborts with an explanation once the entry limit is passed
+func TestMaxWalkEntries(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"a.go":         "package a\n",
+		"b.go":         "package b\n",
+		".hidden/c.go": "package c\n",
+		"d/e.go":       "package d\n",
+	})
+	outputDir := createTempDir(t, "colligo_output")
+
+	// The root, three files and two directories are visited; the hidden directory is not entered
+	cfg := &Config{RepoPath: repoDir, OutputFile: filepath.Join(outputDir, "limited.txt"), MaxWalkEntries: 5}
+	err := run(context.Background(), getLogger(), cfg)
+	if err == nil || !strings.Contains(err.Error(), "-max-walk-entries") {
+		t.Errorf("Expected the walk limit error to mention -max-walk-entries, got %v", err)
+	}
+
+	cfg = &Config{RepoPath: repoDir, OutputFile: filepath.Join(outputDir, "unlimited.txt"), MaxWalkEntries: 6}
+	if err := run(context.Background(), getLogger(), cfg); err != nil {
+		t.Errorf("Expected a walk within the limit to succeed, got %v", err)
+	}
+}
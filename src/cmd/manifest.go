@@ -0,0 +1,145 @@
+// File: src/cmd/manifest.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// manifestEntry is one file listed in a manifest, with optional per-file directives
+// that override the global transform flags
+type manifestEntry struct {
+	Path          string `json:"path"`
+	Head          *int   `json:"head,omitempty"`
+	StripComments *bool  `json:"strip-comments,omitempty"`
+}
+
+// readManifest parses a manifest, either a JSON array of entries or one entry per line.
+// In the line format each line is a path followed by optional directives such as
+// "head=20" or "strip-comments"; blank lines and lines starting with # are ignored.
+func readManifest(r io.Reader) ([]manifestEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("[")) {
+		var entries []manifestEntry
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&entries); err != nil {
+			return nil, fmt.Errorf("invalid JSON manifest: %w", err)
+		}
+		for i, entry := range entries {
+			if entry.Path == "" {
+				return nil, fmt.Errorf("manifest entry %d has no path", i+1)
+			}
+		}
+		return entries, nil
+	}
+
+	var entries []manifestEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, err := parseManifestLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("manifest line %d: %w", lineNumber, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// parseManifestLine splits a manifest line into its path and trailing directives.
+// Directives are consumed from the end of the line, so paths may contain spaces.
+func parseManifestLine(line string) (manifestEntry, error) {
+	var entry manifestEntry
+	fields := strings.Fields(line)
+	end := len(fields)
+	for end > 1 {
+		key, value, hasValue := strings.Cut(fields[end-1], "=")
+		switch key {
+		case "head":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return entry, fmt.Errorf("invalid head directive %q", fields[end-1])
+			}
+			entry.Head = &n
+		case "strip-comments":
+			enabled := true
+			if hasValue {
+				b, err := strconv.ParseBool(value)
+				if err != nil {
+					return entry, fmt.Errorf("invalid strip-comments directive %q", fields[end-1])
+				}
+				enabled = b
+			}
+			entry.StripComments = &enabled
+		default:
+			entry.Path = strings.Join(fields[:end], " ")
+			return entry, nil
+		}
+		end--
+	}
+	entry.Path = strings.Join(fields[:end], " ")
+	return entry, nil
+}
+
+// manifestFiles returns the files listed in the manifest, in manifest order, with
+// their directives applied on top of the global settings
+func manifestFiles(logger *slog.Logger, cfg *Config) ([]fileEntry, error) {
+	var r io.Reader = os.Stdin
+	if cfg.Manifest != "-" {
+		file, err := os.Open(cfg.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	listed, err := readManifest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []fileEntry
+	for _, item := range listed {
+		relativePath := filepath.Clean(filepath.FromSlash(item.Path))
+		path := filepath.Join(cfg.RepoPath, relativePath)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			logger.Error("Error reading manifest file", "file", item.Path, "error", err)
+			continue
+		}
+		if info.IsDir() {
+			logger.Warn("Skipping directory listed in manifest", "path", item.Path)
+			continue
+		}
+
+		entry := newFileEntry(cfg, path, relativePath, info)
+		if item.Head != nil {
+			entry.Head = *item.Head
+		}
+		if item.StripComments != nil {
+			entry.StripComments = *item.StripComments
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,88 @@
+// File: src/cmd/manifest_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestReadManifestLines checks the line-based manifest format
+func TestReadManifestLines(t *testing.T) {
+	input := "# comment\nmain.go head=2\n\ndocs/my notes.md strip-comments\nlib.go strip-comments=false head=0\n"
+	entries, err := readManifest(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	if entries[0].Path != "main.go" || entries[0].Head == nil || *entries[0].Head != 2 || entries[0].StripComments != nil {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Path != "docs/my notes.md" || entries[1].StripComments == nil || !*entries[1].StripComments {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+	if entries[2].StripComments == nil || *entries[2].StripComments || entries[2].Head == nil || *entries[2].Head != 0 {
+		t.Errorf("Unexpected third entry: %+v", entries[2])
+	}
+}
+
+// TestReadManifestJSON checks the JSON manifest format and its validation
+func TestReadManifestJSON(t *testing.T) {
+	entries, err := readManifest(strings.NewReader(`[{"path": "a.go", "head": 20}, {"path": "b.py", "strip-comments": true}]`))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if len(entries) != 2 || *entries[0].Head != 20 || !*entries[1].StripComments {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	if _, err := readManifest(strings.NewReader(`[{"path": "a.go", "haed": 20}]`)); err == nil {
+		t.Errorf("Expected an error for an unknown directive")
+	}
+	if _, err := readManifest(strings.NewReader(`[{"head": 20}]`)); err == nil {
+		t.Errorf("Expected an error for an entry without a path")
+	}
+}
+
+// TestManifestDirectivesOverrideFlags checks that per-file directives win over global flags
+func TestManifestDirectivesOverrideFlags(t *testing.T) {
+	logger := getLogger()
+	repoDir := createTempDir(t, "colligo_manifest_repo")
+	outDir := createTempDir(t, "colligo_manifest_out")
+
+	if err := os.WriteFile(filepath.Join(repoDir, "a.go"), []byte("// a\nline1\nline2\nline3\n"), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "b.go"), []byte("// b\nline1\nline2\nline3\n"), 0644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+	manifestPath := filepath.Join(outDir, "manifest.txt")
+	if err := os.WriteFile(manifestPath, []byte("b.go strip-comments=false\na.go head=1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	cfg := &Config{
+		RepoPath:      repoDir,
+		OutputFile:    filepath.Join(outDir, "combined.txt"),
+		Manifest:      manifestPath,
+		StripComments: true,
+	}
+	if err := run(context.Background(), logger, cfg); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	output, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	expected := "\n\n# BEGIN FILE: b.go\n\n// b\nline1\nline2\nline3\n\n\n# END FILE: b.go\n\n" +
+		"\n\n# BEGIN FILE: a.go\n\nline1\n\n\n# END FILE: a.go\n\n"
+	if string(output) != expected {
+		t.Errorf("Output mismatch. Expected:\n%q\nGot:\n%q", expected, string(output))
+	}
+}
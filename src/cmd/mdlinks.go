@@ -0,0 +1,85 @@
+// File: src/cmd/mdlinks.go
+package main
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// Matches an inline link or image: the text part, the destination and an optional title
+	mdInlineLinkPattern = regexp.MustCompile(`(!?\[[^\]\n]*\])\(([^()\s]+)((?:[ \t]+"[^"\n]*")?)\)`)
+	// Matches a link reference definition such as [id]: docs/guide.md
+	mdLinkDefinitionPattern = regexp.MustCompile(`(?m)^([ ]{0,3}\[[^\]\n]+\]:[ \t]*)(\S+)`)
+)
+
+// rewriteMarkdownLinks points the relative links of a Markdown file at their targets
+// within the combined output: the heading anchor of the linked file, or the file
+// under repoURL when one is given. Images are only rewritten with repoURL, since an
+// anchor cannot stand in for an image. Absolute, external and fragment-only links
+// are left untouched, as are links that leave the repository.
+func rewriteMarkdownLinks(content []byte, relativePath string, repoURL string) []byte {
+	content = mdInlineLinkPattern.ReplaceAllFunc(content, func(match []byte) []byte {
+		parts := mdInlineLinkPattern.FindSubmatch(match)
+		image := parts[1][0] == '!'
+		target, ok := rewriteMarkdownTarget(string(parts[2]), relativePath, repoURL, image)
+		if !ok {
+			return match
+		}
+		return []byte(string(parts[1]) + "(" + target + string(parts[3]) + ")")
+	})
+	return mdLinkDefinitionPattern.ReplaceAllFunc(content, func(match []byte) []byte {
+		parts := mdLinkDefinitionPattern.FindSubmatch(match)
+		target, ok := rewriteMarkdownTarget(string(parts[2]), relativePath, repoURL, false)
+		if !ok {
+			return match
+		}
+		return []byte(string(parts[1]) + target)
+	})
+}
+
+// rewriteMarkdownTarget resolves one link destination, reporting false when it
+// should be left as it is
+func rewriteMarkdownTarget(target string, relativePath string, repoURL string, image bool) (string, bool) {
+	if target == "" || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/") {
+		return "", false
+	}
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" {
+		return "", false
+	}
+	linked := path.Join(path.Dir(filepath.ToSlash(relativePath)), parsed.Path)
+	if linked == "." || linked == ".." || strings.HasPrefix(linked, "../") {
+		return "", false
+	}
+	if repoURL != "" {
+		rewritten := strings.TrimSuffix(repoURL, "/") + "/" + linked
+		if parsed.Fragment != "" {
+			rewritten += "#" + parsed.Fragment
+		}
+		return rewritten, true
+	}
+	if image {
+		return "", false
+	}
+	return "#" + headingAnchor(linked), true
+}
+
+// headingAnchor returns the GitHub-style anchor of a heading: lowercased, with
+// spaces turned into hyphens and other punctuation dropped
+func headingAnchor(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+// File: src/cmd/mdlinks_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRewriteMarkdownLinks checks which links are rewritten, with and without a repository URL
+func TestRewriteMarkdownLinks(t *testing.T) {
+	content := strings.Join([]string{
+		"See [the guide](guide.md) and [install](../INSTALL.md#linux \"Install\").",
+		"![diagram](img/arch.png)",
+		"[site](https://example.com/docs.md) [top](#usage) [root](/abs.md) [mail](mailto:a@example.com)",
+		"[outside](../../elsewhere.md)",
+		"[ref]: api/Reference.md",
+		"",
+	}, "\n")
+
+	cases := []struct {
+		name     string
+		repoURL  string
+		expected string
+	}{
+		{"Anchors", "", strings.Join([]string{
+			"See [the guide](#docsguidemd) and [install](#installmd \"Install\").",
+			"![diagram](img/arch.png)",
+			"[site](https://example.com/docs.md) [top](#usage) [root](/abs.md) [mail](mailto:a@example.com)",
+			"[outside](../../elsewhere.md)",
+			"[ref]: #docsapireferencemd",
+			"",
+		}, "\n")},
+		{"Repository URL", "https://github.com/org/repo/blob/main/", strings.Join([]string{
+			"See [the guide](https://github.com/org/repo/blob/main/docs/guide.md) and [install](https://github.com/org/repo/blob/main/INSTALL.md#linux \"Install\").",
+			"![diagram](https://github.com/org/repo/blob/main/docs/img/arch.png)",
+			"[site](https://example.com/docs.md) [top](#usage) [root](/abs.md) [mail](mailto:a@example.com)",
+			"[outside](../../elsewhere.md)",
+			"[ref]: https://github.com/org/repo/blob/main/docs/api/Reference.md",
+			"",
+		}, "\n")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := string(rewriteMarkdownLinks([]byte(content), "docs/README.md", c.repoURL))
+			if got != c.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", c.expected, got)
+			}
+		})
+	}
+}
+
+// TestRewriteMDLinksMarkdownOutput checks that rewritten links match the headings of the markdown format
+func TestRewriteMDLinksMarkdownOutput(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"README.md":          "Read [the guide](docs/user-guide.md).\n",
+		"docs/user-guide.md": "# Guide\n",
+		"notes.txt":          "[not markdown](docs/user-guide.md)\n",
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "markdown", RewriteMDLinks: true})
+
+	if !strings.Contains(output, "## docs/user-guide.md\n") || !strings.Contains(output, "Read [the guide](#docsuser-guidemd).\n") {
+		t.Errorf("Expected the link to point at the heading of docs/user-guide.md, got:\n%s", output)
+	}
+	if !strings.Contains(output, "[not markdown](docs/user-guide.md)\n") {
+		t.Errorf("Expected links outside Markdown files to be left alone, got:\n%s", output)
+	}
+}
@@ -0,0 +1,84 @@
+// File: src/cmd/migrations.go
+package main
+
+import (
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// Paths of framework-generated migrations dropped by -exclude-migrations. Each pattern
+// captures the directory holding the migration history and the version number of one
+// migration.
+var migrationPatterns = []*regexp.Regexp{
+	// Django: app/migrations/0001_initial.py
+	regexp.MustCompile(`^(|.*/)(migrations)/(\d{4})_\w+\.py$`),
+	// Rails: db/migrate/20240101120000_create_users.rb
+	regexp.MustCompile(`^(|.*/)(db/migrate)/(\d{14})_\w+\.rb$`),
+	// Prisma: prisma/migrations/20240101120000_init/migration.sql
+	regexp.MustCompile(`^(|.*/)(migrations)/(\d{14})_[^/]+/migration\.sql$`),
+	// golang-migrate: migrations/000001_create_users.up.sql and its .down.sql
+	regexp.MustCompile(`^(|.*/)(migrations)/(\d+)_[^/]+\.(?:up|down)\.sql$`),
+}
+
+// migrationOf reports whether rel is a generated migration, returning the directory
+// holding its history and its version number
+func migrationOf(rel string) (dir string, version uint64, ok bool) {
+	for _, pattern := range migrationPatterns {
+		match := pattern.FindStringSubmatch(rel)
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		return path.Join(match[1], match[2]), version, true
+	}
+	return "", 0, false
+}
+
+// excludeMigrations drops generated migrations from entries, keeping the latest keep
+// versions of each migration history. Versions are compared as numbers, so up and
+// down files of one golang-migrate version are kept or dropped together. Files
+// listed in forced are always kept.
+func excludeMigrations(logger *slog.Logger, entries []fileEntry, keep int, forced []string) []fileEntry {
+	versions := make(map[string][]uint64)
+	for _, entry := range entries {
+		if dir, version, ok := migrationOf(slashPath(entry.RelativePath)); ok && !slices.Contains(versions[dir], version) {
+			versions[dir] = append(versions[dir], version)
+		}
+	}
+	// The lowest version that is still kept in each history
+	oldestKept := make(map[string]uint64, len(versions))
+	for dir, list := range versions {
+		if keep <= 0 {
+			oldestKept[dir] = ^uint64(0)
+			continue
+		}
+		slices.Sort(list)
+		oldestKept[dir] = list[max(len(list)-keep, 0)]
+	}
+
+	forcedPaths := make(map[string]bool, len(forced))
+	for _, p := range forced {
+		forcedPaths[slashPath(p)] = true
+	}
+	excluded := 0
+	kept := entries[:0]
+	for _, entry := range entries {
+		rel := slashPath(entry.RelativePath)
+		if dir, version, ok := migrationOf(rel); ok && version < oldestKept[dir] && !forcedPaths[rel] {
+			logger.Debug("Skipping file", "path", entry.RelativePath, "reason", "migration")
+			excluded++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if excluded > 0 {
+		logger.Info("Excluded generated migrations", "files", excluded, "keepLatest", keep)
+	}
+	return kept
+}
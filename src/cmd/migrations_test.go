@@ -0,0 +1,53 @@
+// File: src/cmd/migrations_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestExcludeMigrations checks that generated migrations are dropped except the latest
+// of each history, and that similar-looking files are left alone
+func TestExcludeMigrations(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"app/migrations/__init__.py":                               "",
+		"app/migrations/0001_initial.py":                           "django 1\n",
+		"app/migrations/0002_add_email.py":                         "django 2\n",
+		"app/migrations/0003_add_index.py":                         "django 3\n",
+		"db/migrate/20240101120000_create_users.rb":                "rails 1\n",
+		"db/migrate/20240202120000_add_name.rb":                    "rails 2\n",
+		"prisma/migrations/20240101120000_init/migration.sql":      "prisma 1\n",
+		"prisma/migrations/20240301120000_add_posts/migration.sql": "prisma 2\n",
+		"prisma/migrations/migration_lock.toml":                    "lock\n",
+		"sql/migrations/9_create_users.up.sql":                     "migrate 9 up\n",
+		"sql/migrations/9_create_users.down.sql":                   "migrate 9 down\n",
+		"sql/migrations/10_add_name.up.sql":                        "migrate 10 up\n",
+		"sql/migrations/10_add_name.down.sql":                      "migrate 10 down\n",
+		"app/models.py":                                            "models\n",
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, ExcludeMigrations: true})
+	for _, dropped := range []string{"django", "rails", "prisma 1", "prisma 2", "migrate 9", "migrate 10"} {
+		if strings.Contains(output, dropped) {
+			t.Errorf("Expected %q to be excluded, got:\n%s", dropped, output)
+		}
+	}
+	for _, kept := range []string{"app/migrations/__init__.py", "migration_lock.toml", "models\n"} {
+		if !strings.Contains(output, kept) {
+			t.Errorf("Expected %q to be kept, got:\n%s", kept, output)
+		}
+	}
+
+	output = runAndReadOutput(t, &Config{RepoPath: repoDir, ExcludeMigrations: true, KeepLatestMigrations: 1, ForceInclude: []string{"app/migrations/0001_initial.py"}})
+	for _, kept := range []string{"django 1", "django 3", "rails 2", "prisma 2", "migrate 10 up", "migrate 10 down"} {
+		if !strings.Contains(output, kept) {
+			t.Errorf("Expected %q to be kept, got:\n%s", kept, output)
+		}
+	}
+	// Versions are compared as numbers, so 9 is older than 10
+	for _, dropped := range []string{"django 2", "rails 1", "prisma 1", "migrate 9"} {
+		if strings.Contains(output, dropped) {
+			t.Errorf("Expected %q to be excluded, got:\n%s", dropped, output)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// File: src/cmd/output.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// outputSink is one output file being written in one format
+type outputSink struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	format formatter
+	// hash sees every byte written to the file when -verify-output is set
+	hash hash.Hash
+	// checksum sees every byte before the -output-checksum trailer
+	checksum hash.Hash
+	// trailerMarkers give the comment style of the trailer, which follows
+	// -header-comment-style in txt output
+	trailerMarkers fileMarkers
+}
+
+// Formats that can end with a "# OUTPUT-SHA256:" line and stay valid; an empty
+// format is txt
+var checksumTrailerFormats = map[string]bool{"": true, "txt": true, "compact": true, "markdown": true, "org": true}
+
+// outputTarget names an output file and the format it is written in
+type outputTarget struct {
+	format string
+	path   string
+}
+
+// outputTargets lists the files a run writes: the single -output in -format, or one
+// sibling file per entry of -formats named after the output base plus the format's
+// extension
+func outputTargets(cfg *Config) []outputTarget {
+	if len(cfg.Formats) == 0 {
+		return []outputTarget{{format: cfg.Format, path: cfg.OutputFile}}
+	}
+	base := strings.TrimSuffix(cfg.OutputFile, filepath.Ext(cfg.OutputFile))
+	targets := make([]outputTarget, 0, len(cfg.Formats))
+	for _, name := range cfg.Formats {
+		targets = append(targets, outputTarget{format: name, path: base + formatExtensions[name]})
+	}
+	return targets
+}
+
+// validateFormats checks every requested format before any file is touched
+func validateFormats(cfg *Config) error {
+	for _, target := range outputTargets(cfg) {
+		if _, err := newFormatter(target.format, cfg, &runState{}); err != nil {
+			return err
+		}
+	}
+	seen := make(map[string]bool)
+	for _, name := range cfg.Formats {
+		if seen[name] {
+			return fmt.Errorf("format %q listed more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// openSinks creates the output files for all targets
+func openSinks(ctx context.Context, cfg *Config, state *runState) ([]*outputSink, error) {
+	var sinks []*outputSink
+	for _, target := range outputTargets(cfg) {
+		format, err := newFormatter(target.format, cfg, state)
+		if err != nil {
+			closeSinks(nil, sinks)
+			return nil, err
+		}
+		if state.prompt != nil && promptFormats[target.format] {
+			format = promptFormatter{formatter: format, template: state.prompt}
+		}
+		file, err := createOutputFile(ctx, cfg, target.path)
+		if err != nil {
+			closeSinks(nil, sinks)
+			return nil, err
+		}
+		sink := &outputSink{path: target.path, file: file, format: format}
+		var w io.Writer = file
+		if cfg.VerifyOutput {
+			sink.hash = sha256.New()
+			w = io.MultiWriter(file, sink.hash)
+		}
+		if cfg.OutputChecksum && checksumTrailerFormats[target.format] {
+			sink.checksum = sha256.New()
+			w = io.MultiWriter(w, sink.checksum)
+			sink.trailerMarkers = markersFor("", "")
+			if target.format == "" || target.format == "txt" {
+				sink.trailerMarkers = markersFor(cfg.HeaderCommentStyle, "")
+			}
+		}
+		sink.writer = bufio.NewWriterSize(w, ioBufferSize(cfg))
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// verifySinks reads every flushed output back through open and compares its SHA-256
+// with the hash of the bytes written, to catch silent storage or filesystem errors
+func verifySinks(sinks []*outputSink, open func(path string) (io.ReadCloser, error)) error {
+	for _, sink := range sinks {
+		if sink.hash == nil {
+			continue
+		}
+		file, err := open(sink.path)
+		if err != nil {
+			return fmt.Errorf("verifying %s: %w", sink.path, err)
+		}
+		readBack := sha256.New()
+		_, err = io.Copy(readBack, file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("verifying %s: %w", sink.path, err)
+		}
+		if written, read := sink.hash.Sum(nil), readBack.Sum(nil); !bytes.Equal(written, read) {
+			return fmt.Errorf("verifying %s: wrote sha256 %x but read back %x", sink.path, written, read)
+		}
+	}
+	return nil
+}
+
+// writeChecksumTrailer flushes the sink so its checksum covers everything written,
+// then appends the checksum as the final line
+func writeChecksumTrailer(sink *outputSink) error {
+	if err := sink.writer.Flush(); err != nil {
+		return err
+	}
+	_, err := sink.writer.WriteString(sink.trailerMarkers.comment(fmt.Sprintf("OUTPUT-SHA256: %x", sink.checksum.Sum(nil))))
+	return err
+}
+
+// flushSinks flushes every sink, returning the first error
+func flushSinks(sinks []*outputSink) error {
+	var first error
+	for _, sink := range sinks {
+		if err := sink.writer.Flush(); err != nil && first == nil {
+			first = fmt.Errorf("flushing %s: %w", sink.path, err)
+		}
+	}
+	return first
+}
+
+// closeSinks closes every sink's file, logging failures
+func closeSinks(logger *slog.Logger, sinks []*outputSink) {
+	for _, sink := range sinks {
+		if err := sink.file.Close(); err != nil && logger != nil {
+			logger.Error("Error closing output file", "outputFile", sink.path, "error", err)
+		}
+	}
+}
+
+// parseCommaList splits a comma-separated flag value such as -formats, dropping empty items
+func parseCommaList(value string) []string {
+	var formats []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			formats = append(formats, name)
+		}
+	}
+	return formats
+}
@@ -0,0 +1,228 @@
+// File: src/cmd/output_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestOutputTargets checks how output paths are derived for one or many formats
+func TestOutputTargets(t *testing.T) {
+	single := outputTargets(&Config{Format: "org", OutputFile: "out/dump.org"})
+	if !reflect.DeepEqual(single, []outputTarget{{format: "org", path: "out/dump.org"}}) {
+		t.Errorf("Unexpected single target: %+v", single)
+	}
+
+	multi := outputTargets(&Config{OutputFile: "out/dump.txt", Formats: []string{"txt", "markdown", "json"}})
+	expected := []outputTarget{
+		{format: "txt", path: "out/dump.txt"},
+		{format: "markdown", path: "out/dump.md"},
+		{format: "json", path: "out/dump.json"},
+	}
+	if !reflect.DeepEqual(multi, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, multi)
+	}
+
+	if err := validateFormats(&Config{Formats: []string{"txt", "txt"}}); err == nil {
+		t.Errorf("Expected an error for a duplicated format")
+	}
+	if err := validateFormats(&Config{Formats: []string{"txt", "pdf"}}); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
+
+// TestMultipleFormats checks that one run writes a sibling file per format
+func TestMultipleFormats(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{"main.go": "package main\n", "README.md": "# Demo\n"})
+	outDir := createTempDir(t, "colligo_formats_out")
+
+	cfg := &Config{
+		RepoPath:   repoDir,
+		OutputFile: filepath.Join(outDir, "dump.txt"),
+		Formats:    parseCommaList("txt, markdown,json"),
+		Summary:    true,
+	}
+	runAndReadOutput(t, cfg)
+
+	txt, err := os.ReadFile(filepath.Join(outDir, "dump.txt"))
+	if err != nil || !strings.Contains(string(txt), "# BEGIN FILE: main.go\n\npackage main\n") {
+		t.Errorf("Unexpected txt output (err=%v):\n%s", err, txt)
+	}
+
+	markdown, err := os.ReadFile(filepath.Join(outDir, "dump.md"))
+	if err != nil || !strings.Contains(string(markdown), "## main.go\n\n```go\npackage main\n```\n") {
+		t.Errorf("Unexpected markdown output (err=%v):\n%s", err, markdown)
+	}
+	if !strings.Contains(string(markdown), "## README.md\n\n```markdown\n# Demo\n```\n") {
+		t.Errorf("Expected README.md in a markdown fence:\n%s", markdown)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "dump.json"))
+	if err != nil {
+		t.Fatalf("Failed to read JSON output: %v", err)
+	}
+	var doc struct {
+		Files []struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		} `json:"files"`
+		Summary struct {
+			Files int `json:"files"`
+		} `json:"summary"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("JSON output is not valid: %v\n%s", err, data)
+	}
+	if len(doc.Files) != 2 || doc.Summary.Files != 2 {
+		t.Errorf("Expected 2 file records and a summary, got %+v", doc)
+	}
+	for _, file := range doc.Files {
+		if file.Path == "main.go" && file.Content != "package main\n" {
+			t.Errorf("Unexpected JSON content for main.go: %q", file.Content)
+		}
+	}
+}
+
+// recordingWriter remembers the size of every write it receives
+type recordingWriter struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return w.Buffer.Write(p)
+}
+
+// TestBufferSize checks that output is flushed in chunks of -buffer-size
+func TestBufferSize(t *testing.T) {
+	const bufferSize = 1024
+	sinks, err := openSinks(context.Background(), &Config{
+		OutputFile: filepath.Join(createTempDir(t, "colligo_output"), "combined.txt"),
+		BufferSize: bufferSize,
+	}, &runState{})
+	if err != nil {
+		t.Fatalf("Failed to open sinks: %v", err)
+	}
+	defer closeSinks(nil, sinks)
+
+	// Reset keeps the buffer, so the sink's writer can be pointed at the recorder
+	recorder := &recordingWriter{}
+	writer := sinks[0].writer
+	writer.Reset(recorder)
+	for i := 0; i < 10; i++ {
+		if _, err := writer.WriteString(strings.Repeat("x", 300)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	if len(recorder.writes) != 3 {
+		t.Fatalf("Expected 3000 bytes to take 3 writes with a 1KB buffer, got %v", recorder.writes)
+	}
+	for _, size := range recorder.writes[:2] {
+		if size != bufferSize {
+			t.Errorf("Expected full-buffer writes of %d bytes, got %v", bufferSize, recorder.writes)
+		}
+	}
+}
+
+// TestBufferSizeOutputIdentical checks that the buffer size never changes the output
+func TestBufferSizeOutputIdentical(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"main.go":      "package main\n",
+		"docs/big.txt": strings.Repeat("line of text\n", 5000),
+	})
+
+	small := runAndReadOutput(t, &Config{RepoPath: repoDir, BufferSize: 16})
+	large := runAndReadOutput(t, &Config{RepoPath: repoDir, BufferSize: 1 << 20})
+	if small != large {
+		t.Errorf("Expected identical output for 16B and 1MB buffers")
+	}
+}
+
+// TestVerifyOutput checks that a clean output verifies and a corrupted read-back is detected
+func TestVerifyOutput(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{"main.go": "package main\n"})
+
+	// A normal run verifies against the file on disk
+	runAndReadOutput(t, &Config{RepoPath: repoDir, VerifyOutput: true})
+
+	cfg := &Config{
+		RepoPath:     repoDir,
+		OutputFile:   filepath.Join(createTempDir(t, "colligo_output"), "combined.txt"),
+		VerifyOutput: true,
+	}
+	sinks, err := openSinks(context.Background(), cfg, &runState{})
+	if err != nil {
+		t.Fatalf("Failed to open sinks: %v", err)
+	}
+	defer closeSinks(nil, sinks)
+	if _, err := sinks[0].writer.WriteString("intended content\n"); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if err := flushSinks(sinks); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	intact := func(path string) (io.ReadCloser, error) { return os.Open(path) }
+	if err := verifySinks(sinks, intact); err != nil {
+		t.Errorf("Expected intact output to verify, got %v", err)
+	}
+
+	// Flip one byte on the way back in, as a faulty disk would
+	corrupted := func(path string) (io.ReadCloser, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		data[0] ^= 0xff
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	if err := verifySinks(sinks, corrupted); err == nil || !strings.Contains(err.Error(), "read back") {
+		t.Errorf("Expected corruption to be detected, got %v", err)
+	}
+}
+
+// TestOutputChecksum checks that the trailer is the SHA-256 of everything before it
+func TestOutputChecksum(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{"main.go": "package main\n", "README.md": "# Readme\n"})
+	outputDir := createTempDir(t, "colligo_output")
+
+	cfg := &Config{
+		RepoPath:       repoDir,
+		OutputFile:     filepath.Join(outputDir, "combined.txt"),
+		Formats:        []string{"txt", "json"},
+		OutputChecksum: true,
+		VerifyOutput:   true,
+		Summary:        true,
+	}
+	output := runAndReadOutput(t, cfg)
+
+	body, trailer, found := strings.Cut(output, "# OUTPUT-SHA256: ")
+	if !found || strings.Contains(trailer, "\n# OUTPUT-SHA256: ") {
+		t.Fatalf("Expected exactly one checksum trailer, got:\n%s", output)
+	}
+	if expected := fmt.Sprintf("%x\n", sha256.Sum256([]byte(body))); trailer != expected {
+		t.Errorf("Expected trailer %q, got %q", expected, trailer)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "combined.json"))
+	if err != nil {
+		t.Fatalf("Failed to read JSON output: %v", err)
+	}
+	if !json.Valid(data) {
+		t.Errorf("Expected the JSON output to stay valid, got:\n%s", data)
+	}
+}
@@ -0,0 +1,64 @@
+// File: src/cmd/prompt.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The placeholder in a -prompt-template file that the combined output replaces
+const promptPlaceholder = "{context}"
+
+// Formats a prompt template can wrap and leave readable; wrapping the others, such as
+// json or html, would make them invalid. An empty format is txt.
+var promptFormats = map[string]bool{"": true, "txt": true, "compact": true, "markdown": true, "org": true}
+
+// promptTemplate is a -prompt-template file split around its placeholder
+type promptTemplate struct {
+	before string
+	after  string
+}
+
+// loadPromptTemplate reads a -prompt-template file, which must hold the placeholder
+// exactly once
+func loadPromptTemplate(name string) (*promptTemplate, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+	if count := strings.Count(text, promptPlaceholder); count != 1 {
+		return nil, fmt.Errorf("prompt template %s must contain %s exactly once, found %d", name, promptPlaceholder, count)
+	}
+	before, after, _ := strings.Cut(text, promptPlaceholder)
+	return &promptTemplate{before: before, after: after}, nil
+}
+
+// promptFormatter wraps the output of another format in a prompt template
+type promptFormatter struct {
+	formatter
+	template *promptTemplate
+}
+
+func (f promptFormatter) begin(w *bufio.Writer) error {
+	if _, err := w.WriteString(f.template.before); err != nil {
+		return err
+	}
+	return f.formatter.begin(w)
+}
+
+func (f promptFormatter) end(w *bufio.Writer, summary *runStats) error {
+	if err := f.formatter.end(w, summary); err != nil {
+		return err
+	}
+	_, err := w.WriteString(f.template.after)
+	return err
+}
+
+// collides passes the check on to the wrapped format
+func (f promptFormatter) collides(content []byte) bool {
+	checker, ok := f.formatter.(collisionChecker)
+	return ok && checker.collides(content)
+}
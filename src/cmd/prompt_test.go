@@ -0,0 +1,50 @@
+// File: src/cmd/prompt_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPromptTemplate checks that the combined output replaces the placeholder of the
+// prompt template, that formats it would break are left unwrapped, and that a
+// template without exactly one placeholder is rejected
+func TestPromptTemplate(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{"main.go": "package main\n"})
+	templateDir := createTempDir(t, "colligo_prompt")
+	templatePath := filepath.Join(templateDir, "prompt.txt")
+	template := "You are reviewing a Go repository.\n\n<context>\n{context}\n</context>\n\nList any bugs you find.\n"
+	if err := os.WriteFile(templatePath, []byte(template), 0644); err != nil {
+		t.Fatalf("Failed to write prompt template: %v", err)
+	}
+
+	plain := runAndReadOutput(t, &Config{RepoPath: repoDir})
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, PromptTemplate: templatePath})
+	expected := strings.Replace(template, "{context}", plain, 1)
+	if output != expected {
+		t.Errorf("Expected the output inside the prompt:\n%s\ngot:\n%s", expected, output)
+	}
+
+	plain = runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "json"})
+	output = runAndReadOutput(t, &Config{RepoPath: repoDir, Format: "json", PromptTemplate: templatePath})
+	if output != plain {
+		t.Errorf("Expected json output without the prompt:\n%s\ngot:\n%s", plain, output)
+	}
+
+	for name, content := range map[string]string{
+		"none.txt":  "no placeholder\n",
+		"twice.txt": "{context}\n{context}\n",
+	} {
+		path := filepath.Join(templateDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write prompt template: %v", err)
+		}
+		cfg := &Config{RepoPath: repoDir, OutputFile: filepath.Join(templateDir, "out.txt"), PromptTemplate: path}
+		if err := run(context.Background(), getLogger(), cfg); err == nil || !strings.Contains(err.Error(), "exactly once") {
+			t.Errorf("Expected %s to be rejected, got %v", name, err)
+		}
+	}
+}
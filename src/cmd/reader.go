@@ -0,0 +1,212 @@
+// File: src/cmd/reader.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// contentReader loads the raw content of a file selected for the output
+type contentReader interface {
+	read(entry fileEntry) ([]byte, error)
+}
+
+// errReadTimeout is returned when a file read takes longer than its timeout
+var errReadTimeout = errors.New("read timed out")
+
+// fileReader reads files from disk, optionally enforcing per-file timeouts
+type fileReader struct {
+	// tracker derives per-file timeouts from observed throughput; nil disables them
+	tracker *throughputTracker
+	// open is replaceable so tests can simulate slow files
+	open func(path string) (io.ReadCloser, error)
+	// bufferSize sizes the buffered reader wrapped around each file
+	bufferSize int
+}
+
+// newFileReader returns a reader configured from cfg
+func newFileReader(cfg *Config) *fileReader {
+	r := &fileReader{bufferSize: ioBufferSize(cfg)}
+	if cfg.PerFileTimeoutMultiplier > 0 {
+		r.tracker = newThroughputTracker(cfg.PerFileTimeoutMultiplier)
+	}
+	return r
+}
+
+func (r *fileReader) read(entry fileEntry) ([]byte, error) {
+	open := r.open
+	if open == nil {
+		open = func(path string) (io.ReadCloser, error) { return os.Open(path) }
+	}
+
+	var timeout time.Duration
+	if r.tracker != nil {
+		timeout = r.tracker.timeoutFor(entry.Size)
+	}
+
+	start := time.Now()
+	content, err := readWithTimeout(func() (io.ReadCloser, error) { return open(entry.Path) }, timeout, r.bufferSize)
+	if err != nil {
+		if errors.Is(err, errReadTimeout) {
+			return nil, fmt.Errorf("%w after %s (size %d bytes)", err, timeout, entry.Size)
+		}
+		return nil, err
+	}
+	if r.tracker != nil {
+		r.tracker.observe(int64(len(content)), time.Since(start))
+	}
+	return content, nil
+}
+
+// readWithTimeout reads everything from the opened file through a buffer of
+// bufferSize bytes, giving up after timeout. A zero timeout waits indefinitely. On
+// timeout the file is closed to unblock the pending read, and its result is discarded.
+func readWithTimeout(open func() (io.ReadCloser, error), timeout time.Duration, bufferSize int) ([]byte, error) {
+	file, err := open()
+	if err != nil {
+		return nil, err
+	}
+	var closeOnce sync.Once
+	closeFile := func() { closeOnce.Do(func() { file.Close() }) }
+	defer closeFile()
+
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	buffered := bufio.NewReaderSize(file, bufferSize)
+	if timeout <= 0 {
+		return io.ReadAll(buffered)
+	}
+
+	type result struct {
+		content []byte
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		content, err := io.ReadAll(buffered)
+		done <- result{content, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case res := <-done:
+		return res.content, res.err
+	case <-timer.C:
+		closeFile()
+		return nil, errReadTimeout
+	}
+}
+
+// prefetchedFile is the result of reading one file ahead of the writer
+type prefetchedFile struct {
+	path    string
+	content []byte
+	err     error
+}
+
+// prefetchReader reads files on a background goroutine ahead of the writer, keeping
+// up to -read-ahead results buffered in a channel. Files must be requested in the
+// order they were given.
+type prefetchReader struct {
+	reader  contentReader
+	results chan prefetchedFile
+	cancel  context.CancelFunc
+	// direct is set once the caller got out of step and files are read directly
+	direct bool
+}
+
+// newPrefetchReader starts reading the files behind entries with reader, at most
+// depth files ahead of the caller. Placeholder entries are never read, so they are
+// skipped.
+func newPrefetchReader(ctx context.Context, reader contentReader, entries []fileEntry, depth int) *prefetchReader {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &prefetchReader{reader: reader, results: make(chan prefetchedFile, depth), cancel: cancel}
+	go func() {
+		defer close(p.results)
+		for _, entry := range entries {
+			if entry.Note != "" {
+				continue
+			}
+			content, err := reader.read(entry)
+			select {
+			case p.results <- prefetchedFile{path: entry.Path, content: content, err: err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func (p *prefetchReader) read(entry fileEntry) ([]byte, error) {
+	if !p.direct {
+		if result, ok := <-p.results; ok && result.path == entry.Path {
+			return result.content, result.err
+		}
+		// Out of step with the prefetcher; stop it and wait for its last read to
+		// finish before reading directly from here on
+		p.stop()
+		for range p.results {
+		}
+		p.direct = true
+	}
+	return p.reader.read(entry)
+}
+
+// stop ends the background reads, for example when the run is interrupted
+func (p *prefetchReader) stop() {
+	p.cancel()
+}
+
+// throughputTracker keeps an exponential moving average of read throughput and
+// turns it into a per-file timeout of multiplier * size / throughput
+type throughputTracker struct {
+	multiplier float64
+	// alpha is the weight of the newest observation in the moving average
+	alpha float64
+	// minTimeout keeps small files from timing out on scheduling noise
+	minTimeout     time.Duration
+	bytesPerSecond float64
+}
+
+// Defaults for the throughput tracker behind -per-file-timeout-multiplier
+const (
+	throughputAlpha   = 0.3
+	minPerFileTimeout = time.Second
+)
+
+// newThroughputTracker returns a tracker with the default smoothing and floor
+func newThroughputTracker(multiplier float64) *throughputTracker {
+	return &throughputTracker{multiplier: multiplier, alpha: throughputAlpha, minTimeout: minPerFileTimeout}
+}
+
+// timeoutFor returns the timeout for a file of the given size, or zero while there
+// is no throughput estimate yet
+func (t *throughputTracker) timeoutFor(size int64) time.Duration {
+	if t.bytesPerSecond <= 0 {
+		return 0
+	}
+	expected := time.Duration(float64(size) / t.bytesPerSecond * float64(time.Second))
+	return max(time.Duration(t.multiplier*float64(expected)), t.minTimeout)
+}
+
+// observe folds a completed read into the moving average
+func (t *throughputTracker) observe(size int64, elapsed time.Duration) {
+	if size <= 0 || elapsed <= 0 {
+		return
+	}
+	rate := float64(size) / elapsed.Seconds()
+	if t.bytesPerSecond <= 0 {
+		t.bytesPerSecond = rate
+		return
+	}
+	t.bytesPerSecond = t.alpha*rate + (1-t.alpha)*t.bytesPerSecond
+}
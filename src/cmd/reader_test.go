@@ -0,0 +1,151 @@
+// File: src/cmd/reader_test.go
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowFile is a fake file whose first read blocks for delay
+type slowFile struct {
+	io.Reader
+	delay time.Duration
+	slept bool
+}
+
+func (f *slowFile) Read(p []byte) (int, error) {
+	if !f.slept {
+		f.slept = true
+		time.Sleep(f.delay)
+	}
+	return f.Reader.Read(p)
+}
+
+func (f *slowFile) Close() error { return nil }
+
+// TestThroughputTracker checks the timeout derived from the moving average
+func TestThroughputTracker(t *testing.T) {
+	tracker := &throughputTracker{multiplier: 2, alpha: 0.5}
+	if got := tracker.timeoutFor(1000); got != 0 {
+		t.Errorf("Expected no timeout before any observation, got %s", got)
+	}
+
+	tracker.observe(1000, time.Second)
+	if got := tracker.timeoutFor(500); got != time.Second {
+		t.Errorf("Expected a 1s timeout at 1000 B/s and multiplier 2, got %s", got)
+	}
+
+	tracker.observe(3000, time.Second)
+	if tracker.bytesPerSecond != 2000 {
+		t.Errorf("Expected the moving average to be 2000 B/s, got %v", tracker.bytesPerSecond)
+	}
+
+	tracker.minTimeout = 5 * time.Second
+	if got := tracker.timeoutFor(10); got != 5*time.Second {
+		t.Errorf("Expected the minimum timeout to apply, got %s", got)
+	}
+}
+
+// TestPerFileTimeout checks that a read taking 3x the expected time fails with multiplier 2
+func TestPerFileTimeout(t *testing.T) {
+	const size = 500
+	expected := 50 * time.Millisecond
+
+	tracker := &throughputTracker{multiplier: 2, alpha: throughputAlpha}
+	tracker.observe(size, expected)
+
+	newReader := func(delay time.Duration) *fileReader {
+		return &fileReader{
+			tracker: tracker,
+			open: func(string) (io.ReadCloser, error) {
+				return &slowFile{Reader: strings.NewReader(strings.Repeat("x", size)), delay: delay}, nil
+			},
+		}
+	}
+
+	entry := fileEntry{Path: "slow.txt", RelativePath: "slow.txt", Size: size}
+	if _, err := newReader(3 * expected).read(entry); !errors.Is(err, errReadTimeout) {
+		t.Errorf("Expected a read taking 3x the expected time to time out, got %v", err)
+	}
+
+	content, err := newReader(0).read(entry)
+	if err != nil {
+		t.Fatalf("Expected a fast read to succeed, got %v", err)
+	}
+	if len(content) != size {
+		t.Errorf("Expected %d bytes, got %d", size, len(content))
+	}
+}
+
+// recordingFile is a fake file that remembers the size of every read it receives
+type recordingFile struct {
+	io.Reader
+	reads []int
+}
+
+func (f *recordingFile) Read(p []byte) (int, error) {
+	f.reads = append(f.reads, len(p))
+	return f.Reader.Read(p)
+}
+
+func (f *recordingFile) Close() error { return nil }
+
+// TestReadBufferSize checks that files are read in chunks of -buffer-size
+func TestReadBufferSize(t *testing.T) {
+	const bufferSize = 64 << 10
+	file := &recordingFile{Reader: strings.NewReader(strings.Repeat("x", 200<<10))}
+	reader := &fileReader{
+		bufferSize: bufferSize,
+		open:       func(string) (io.ReadCloser, error) { return file, nil },
+	}
+
+	content, err := reader.read(fileEntry{Path: "big.txt", RelativePath: "big.txt"})
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if len(content) != 200<<10 {
+		t.Errorf("Expected %d bytes, got %d", 200<<10, len(content))
+	}
+	for _, size := range file.reads {
+		if size != bufferSize {
+			t.Errorf("Expected every read to request %d bytes, got %v", bufferSize, file.reads)
+			break
+		}
+	}
+}
+
+// TestReadAheadOutputIdentical checks that prefetching never changes the output
+func TestReadAheadOutputIdentical(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"a.go":          "package a\n",
+		"b/b.go":        "package b\n",
+		"b/c/notes.txt": strings.Repeat("notes\n", 1000),
+		"d.md":          "# D\n",
+		"e.txt":         "",
+	})
+
+	sequential := runAndReadOutput(t, &Config{RepoPath: repoDir})
+	prefetched := runAndReadOutput(t, &Config{RepoPath: repoDir, ReadAhead: 2})
+	if sequential != prefetched {
+		t.Errorf("Expected identical output with -read-ahead 2")
+	}
+}
+
+// TestPrefetchReaderOutOfOrder checks that a request out of order falls back to direct reads
+func TestPrefetchReaderOutOfOrder(t *testing.T) {
+	open := func(path string) (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("content of " + path)), nil }
+	entries := []fileEntry{{Path: "a"}, {Path: "hardlink", Note: "placeholder"}, {Path: "b"}, {Path: "c"}}
+	prefetch := newPrefetchReader(context.Background(), &fileReader{open: open}, entries, 1)
+	defer prefetch.stop()
+
+	for _, path := range []string{"a", "c", "b"} {
+		content, err := prefetch.read(fileEntry{Path: path})
+		if err != nil || string(content) != "content of "+path {
+			t.Errorf("Expected the content of %s, got %q (%v)", path, content, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// File: src/cmd/repostats.go
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Number of extensions listed by -repo-stats-header
+const repoStatsTopExtensions = 5
+
+// repoStats is the overview written at the top of the output by -repo-stats-header.
+// It describes the files selected for the output, so it is computed from the
+// collected entries before anything is written.
+type repoStats struct {
+	Name        string
+	Files       int
+	Directories int
+	TotalBytes  int64
+	Extensions  []extensionCount
+}
+
+// extensionCount is how many selected files share an extension
+type extensionCount struct {
+	Extension string
+	Files     int
+}
+
+// computeRepoStats aggregates the entries of a run. Directories are every directory
+// below the root that holds a selected file, directly or further down.
+func computeRepoStats(repoPath string, entries []fileEntry) *repoStats {
+	stats := &repoStats{Name: filepath.Base(repoPath), Files: len(entries)}
+	directories := make(map[string]bool)
+	extensions := make(map[string]int)
+	for _, entry := range entries {
+		stats.TotalBytes += entry.Size
+		for dir := filepath.Dir(entry.RelativePath); dir != "." && !directories[dir]; dir = filepath.Dir(dir) {
+			directories[dir] = true
+		}
+		ext := strings.ToLower(filepath.Ext(entry.RelativePath))
+		if ext == "" {
+			ext = "(none)"
+		}
+		extensions[ext]++
+	}
+	stats.Directories = len(directories)
+
+	for ext, files := range extensions {
+		stats.Extensions = append(stats.Extensions, extensionCount{Extension: ext, Files: files})
+	}
+	slices.SortFunc(stats.Extensions, func(a, b extensionCount) int {
+		return cmp.Or(cmp.Compare(b.Files, a.Files), cmp.Compare(a.Extension, b.Extension))
+	})
+	if len(stats.Extensions) > repoStatsTopExtensions {
+		stats.Extensions = stats.Extensions[:repoStatsTopExtensions]
+	}
+	return stats
+}
+
+// header renders the stats as a block of comments in the style of markers, followed
+// by a blank line
+func (s *repoStats) header(markers fileMarkers) string {
+	extensions := make([]string, len(s.Extensions))
+	for i, ext := range s.Extensions {
+		extensions[i] = fmt.Sprintf("%s (%d)", ext.Extension, ext.Files)
+	}
+	var b strings.Builder
+	b.WriteString(markers.comment("REPOSITORY: " + s.Name))
+	b.WriteString(markers.comment(fmt.Sprintf("Files: %d", s.Files)))
+	b.WriteString(markers.comment(fmt.Sprintf("Directories: %d", s.Directories)))
+	b.WriteString(markers.comment(fmt.Sprintf("Total bytes: %d", s.TotalBytes)))
+	b.WriteString(markers.comment("Top extensions: "+strings.Join(extensions, ", ")) + "\n")
+	return b.String()
+}
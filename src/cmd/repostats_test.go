@@ -0,0 +1,47 @@
+// File: src/cmd/repostats_test.go
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRepoStatsHeader checks that the overview precedes the first file and matches the fixture
+func TestRepoStatsHeader(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"main.go":             "package main\n",
+		"cmd/tool/tool.go":    "package tool\n",
+		"internal/a.go":       "package internal\n",
+		"docs/guide.md":       "# Guide\n",
+		"docs/api.md":         "# API\n",
+		"web/app.js":          "app()\n",
+		"web/style.css":       "body {}\n",
+		"config/settings.YML": "a: 1\n",
+		"Makefile":            "all:\n",
+	})
+	var totalBytes int
+	for _, content := range []string{"package main\n", "package tool\n", "package internal\n", "# Guide\n", "# API\n", "app()\n", "body {}\n", "a: 1\n", "all:\n"} {
+		totalBytes += len(content)
+	}
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, RepoStatsHeader: true})
+
+	expected := "# REPOSITORY: " + filepath.Base(repoDir) + "\n" +
+		"# Files: 9\n" +
+		"# Directories: 6\n" +
+		"# Total bytes: " + fmt.Sprint(totalBytes) + "\n" +
+		"# Top extensions: .go (3), .md (2), (none) (1), .css (1), .js (1)\n\n"
+	if !strings.HasPrefix(output, expected) {
+		t.Errorf("Expected the output to start with:\n%s\nGot:\n%s", expected, output)
+	}
+	if strings.Index(output, "# REPOSITORY: ") > strings.Index(output, "# BEGIN FILE: ") {
+		t.Errorf("Expected the header before the first BEGIN FILE marker")
+	}
+
+	output = runAndReadOutput(t, &Config{RepoPath: repoDir})
+	if strings.Contains(output, "# REPOSITORY: ") {
+		t.Errorf("Expected no header without -repo-stats-header")
+	}
+}
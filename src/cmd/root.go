@@ -0,0 +1,31 @@
+// File: src/cmd/root.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Files or directories whose presence marks the root of a project, checked by -auto-root
+var rootMarkers = []string{".git", "go.mod", "package.json", ".colligo.yaml", ".colligo.json"}
+
+// findRepoRoot walks up from start to the nearest directory containing a root marker.
+// It reports false if no ancestor has one.
+func findRepoRoot(start string) (string, bool) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", false
+	}
+	for {
+		for _, marker := range rootMarkers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir, true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,59 @@
+// File: src/cmd/root_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFindRepoRoot checks that the nearest marked ancestor is found
+func TestFindRepoRoot(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"go.mod":                    "module example\n",
+		"internal/deep/pkg/file.go": "package pkg\n",
+		"web/package.json":          "{}\n",
+		"web/src/app/index.js":      "\n",
+		"tools/.colligo.json":       "{}\n",
+		"tools/lint/run.sh":         "\n",
+		"ops/.colligo.yaml":         "\n",
+		"ops/deploy/run.sh":         "\n",
+	})
+
+	cases := []struct {
+		name     string
+		start    string
+		expected string
+	}{
+		{"Root Itself", repoDir, repoDir},
+		{"Deep Directory", filepath.Join(repoDir, "internal", "deep", "pkg"), repoDir},
+		{"Nearest Marker Wins", filepath.Join(repoDir, "web", "src", "app"), filepath.Join(repoDir, "web")},
+		{"Config File Marker", filepath.Join(repoDir, "tools", "lint"), filepath.Join(repoDir, "tools")},
+		{"YAML Config File Marker", filepath.Join(repoDir, "ops", "deploy"), filepath.Join(repoDir, "ops")},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			root, ok := findRepoRoot(c.start)
+			if !ok || root != c.expected {
+				t.Errorf("Expected root %s, got %s (found=%v)", c.expected, root, ok)
+			}
+		})
+	}
+}
+
+// TestFindRepoRootGitDirectory checks that a .git directory marks the root
+func TestFindRepoRootGitDirectory(t *testing.T) {
+	repoDir := createTempDir(t, "colligo_root_git")
+	if err := os.MkdirAll(filepath.Join(repoDir, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create .git directory: %v", err)
+	}
+	sub := filepath.Join(repoDir, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+
+	if root, ok := findRepoRoot(sub); !ok || root != repoDir {
+		t.Errorf("Expected root %s, got %s (found=%v)", repoDir, root, ok)
+	}
+}
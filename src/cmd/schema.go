@@ -0,0 +1,297 @@
+// File: src/cmd/schema.go
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The JSON Schema for config files, generated from Config by generateConfigSchema.
+// Regenerate it after changing Config or its flags:
+//
+//go:generate go test -run TestConfigSchemaUpToDate -update-schema
+//go:embed config.schema.json
+var configSchemaJSON []byte
+
+// Allowed values for config keys that only accept a fixed set of strings
+var configEnums = map[string]func() []string{
+	"log-level":                  func() []string { return []string{"debug", "info", "warn", "error"} },
+	"format":                     formatNames,
+	"io-profile":                 ioProfileNames,
+	"sanitize-utf8":              func() []string { return sanitizeUTF8Modes },
+	"transform-order":            transformNames,
+	"delimiter-collision":        func() []string { return delimiterCollisionModes },
+	"header-comment-style":       func() []string { return headerCommentStyles },
+	"formats":                    formatNames,
+	"per-file-truncate-strategy": func() []string { return truncateStrategies },
+	"sort":                       func() []string { return sortOrders },
+}
+
+// generateConfigSchema builds the config file schema by reflecting over Config, taking
+// each property's description from the usage text of the matching flag
+func generateConfigSchema() ([]byte, error) {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	registerFlags(fs, defaultConfig())
+
+	properties := make(map[string]any)
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		property, err := schemaForType(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if f := fs.Lookup(name); f != nil {
+			property["description"] = f.Usage
+		}
+		if values, ok := configEnums[name]; ok {
+			target := property
+			if items, ok := property["items"].(map[string]any); ok {
+				target = items
+			}
+			target["enum"] = values()
+		}
+		properties[name] = property
+	}
+
+	schema := map[string]any{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"title":                "Colligo config file",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties":           properties,
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaForType maps a Go field type onto its JSON Schema
+func schemaForType(t reflect.Type) (map[string]any, error) {
+	// Sizes are written either as a number of bytes or as a string like "1MB"
+	if t == reflect.TypeOf(byteSize(0)) {
+		return map[string]any{"type": []any{"integer", "string"}, "minimum": 1}, nil
+	}
+	// Durations are written as strings like "30s"
+	if t == reflect.TypeOf(duration(0)) {
+		return map[string]any{"type": "string"}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "minimum": 0}, nil
+	case reflect.Float64:
+		return map[string]any{"type": "number", "minimum": 0}, nil
+	case reflect.Slice:
+		items, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Pointer:
+		inner, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		inner["type"] = []any{inner["type"], "null"}
+		return inner, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// schemaNode is the subset of JSON Schema that Colligo's config schema uses
+type schemaNode struct {
+	Type                 any                    `json:"type"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties *additionalProperties  `json:"additionalProperties"`
+	Items                *schemaNode            `json:"items"`
+	Enum                 []any                  `json:"enum"`
+	Minimum              *float64               `json:"minimum"`
+}
+
+// additionalProperties is either false, forbidding unknown keys, or a schema the
+// values of unknown keys must match
+type additionalProperties struct {
+	forbidden bool
+	schema    *schemaNode
+}
+
+func (a *additionalProperties) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		a.forbidden = !allowed
+		return nil
+	}
+	a.schema = &schemaNode{}
+	return json.Unmarshal(data, a.schema)
+}
+
+var (
+	configSchemaOnce sync.Once
+	configSchema     *schemaNode
+	configSchemaErr  error
+)
+
+// validateConfigData checks a config file against the embedded schema and returns
+// every problem found, not just the first
+func validateConfigData(data []byte) []string {
+	configSchemaOnce.Do(func() {
+		configSchema = &schemaNode{}
+		configSchemaErr = json.Unmarshal(configSchemaJSON, configSchema)
+	})
+	if configSchemaErr != nil {
+		return []string{fmt.Sprintf("embedded config schema is invalid: %v", configSchemaErr)}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []string{fmt.Sprintf("not valid JSON: %v", err)}
+	}
+
+	var problems []string
+	configSchema.validate(value, "", &problems)
+	return problems
+}
+
+// validate appends a problem for every way value violates the schema. The path is
+// a JSON pointer to the value.
+func (s *schemaNode) validate(value any, path string, problems *[]string) {
+	where := path
+	if where == "" {
+		where = "(root)"
+	}
+	report := func(format string, args ...any) {
+		*problems = append(*problems, where+": "+fmt.Sprintf(format, args...))
+	}
+
+	if s.Type != nil && !s.matchesType(value) {
+		report("expected %s, got %s", describeSchemaType(s.Type), jsonTypeOf(value))
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("value %v is not one of %v", value, s.Enum)
+		}
+	}
+
+	if s.Minimum != nil {
+		if n, ok := value.(json.Number); ok {
+			if f, err := n.Float64(); err == nil && f < *s.Minimum {
+				report("value %v is less than the minimum %v", n, *s.Minimum)
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := path + "/" + key
+			switch property, ok := s.Properties[key]; {
+			case ok:
+				property.validate(v[key], child, problems)
+			case s.AdditionalProperties == nil:
+			case s.AdditionalProperties.forbidden:
+				*problems = append(*problems, child+": unknown key")
+			case s.AdditionalProperties.schema != nil:
+				s.AdditionalProperties.schema.validate(v[key], child, problems)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%s/%d", path, i), problems)
+			}
+		}
+	}
+}
+
+// matchesType reports whether value has one of the schema's types
+func (s *schemaNode) matchesType(value any) bool {
+	types, ok := s.Type.([]any)
+	if !ok {
+		types = []any{s.Type}
+	}
+	actual := jsonTypeOf(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeOf names the JSON Schema type of a decoded value
+func jsonTypeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// describeSchemaType renders a schema type for error messages
+func describeSchemaType(t any) string {
+	types, ok := t.([]any)
+	if !ok {
+		return fmt.Sprint(t)
+	}
+	names := make([]string, len(types))
+	for i, name := range types {
+		names[i] = fmt.Sprint(name)
+	}
+	return strings.Join(names, " or ")
+}
@@ -0,0 +1,62 @@
+// File: src/cmd/schema_test.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+var updateSchema = flag.Bool("update-schema", false, "Rewrite config.schema.json from the Config struct")
+
+// TestConfigSchemaUpToDate checks that the embedded schema matches the Config struct
+func TestConfigSchemaUpToDate(t *testing.T) {
+	generated, err := generateConfigSchema()
+	if err != nil {
+		t.Fatalf("Failed to generate config schema: %v", err)
+	}
+
+	if *updateSchema {
+		if err := os.WriteFile("config.schema.json", generated, 0644); err != nil {
+			t.Fatalf("Failed to write config schema: %v", err)
+		}
+		return
+	}
+
+	if !bytes.Equal(generated, configSchemaJSON) {
+		t.Errorf("config.schema.json is out of date; run go generate ./cmd")
+	}
+}
+
+// TestValidateConfigData checks that valid configs pass and every problem in an invalid one is reported
+func TestValidateConfigData(t *testing.T) {
+	valid := `{"repo": "src", "max-tokens": 1000, "format": "org", "exclude-pattern": ["*.log"], "block-separator": "---\n", "buffer-size": "1MB", "depth-map": {"docs": 1}}`
+	if problems := validateConfigData([]byte(valid)); len(problems) != 0 {
+		t.Errorf("Expected a valid config, got %v", problems)
+	}
+
+	invalid := `{"repo": 3, "max-tokns": 10, "format": "docx", "exclude-pattern": ["*.log", 5], "head": -1, "depth-map": {"src": "deep"}}`
+	problems := validateConfigData([]byte(invalid))
+	expected := []string{
+		"/depth-map/src: expected integer",
+		"/exclude-pattern/1: expected string",
+		"/format: value docx is not one of",
+		"/head: value -1 is less than the minimum",
+		"/max-tokns: unknown key",
+		"/repo: expected string, got integer",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(problems[i], prefix) {
+			t.Errorf("Expected problem %d to start with %q, got %q", i, prefix, problems[i])
+		}
+	}
+
+	if problems := validateConfigData([]byte(`{"repo": `)); len(problems) != 1 {
+		t.Errorf("Expected a single problem for malformed JSON, got %v", problems)
+	}
+}
@@ -0,0 +1,110 @@
+// File: src/cmd/seenstore.go
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// seenStore is the -seen-store file: the SHA-256 of every file emitted by earlier
+// runs, one lowercase hex digest per line. New digests are appended after a run.
+type seenStore struct {
+	path   string
+	hashes map[string]bool
+	added  []string
+}
+
+// loadSeenStore reads the store at path; a missing file is an empty store
+func loadSeenStore(path string) (*seenStore, error) {
+	store := &seenStore{path: path, hashes: make(map[string]bool)}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		hash := strings.TrimSpace(scanner.Text())
+		if hash == "" {
+			continue
+		}
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("%s:%d: expected a SHA-256 hex digest, got %q", path, line, hash)
+		}
+		store.hashes[strings.ToLower(hash)] = true
+	}
+	return store, scanner.Err()
+}
+
+// mark hashes the content of every entry and turns files already in the store, or
+// repeated earlier in this run, into placeholders. The hashes of the other files
+// are added to the store.
+func (s *seenStore) mark(logger *slog.Logger, entries []fileEntry) {
+	firstPaths := make(map[string]string)
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Note != "" {
+			continue
+		}
+		hash, err := hashFile(entry.Path)
+		if err != nil {
+			// The read fails again, and is reported, when the file is written
+			logger.Warn("Could not hash file for the seen store", "file", entry.RelativePath, "error", err)
+			continue
+		}
+		switch first, ok := firstPaths[hash]; {
+		case ok:
+			logger.Debug("Replacing duplicate file with placeholder", "file", entry.RelativePath, "original", first)
+			entry.Note = fmt.Sprintf("# DUPLICATE OF: %s (sha256=%s)", filepath.ToSlash(first), hash)
+		case s.hashes[hash]:
+			logger.Debug("Replacing previously emitted file with placeholder", "file", entry.RelativePath)
+			entry.Note = fmt.Sprintf("# ALREADY EMITTED (sha256=%s)", hash)
+		default:
+			firstPaths[hash] = entry.RelativePath
+			s.added = append(s.added, hash)
+		}
+	}
+}
+
+// save appends the hashes added by this run to the store file
+func (s *seenStore) save() error {
+	if len(s.added) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(strings.Join(s.added, "\n") + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// hashFile returns the hex SHA-256 of a file's content
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
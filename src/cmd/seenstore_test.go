@@ -0,0 +1,68 @@
+// File: src/cmd/seenstore_test.go
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSeenStore checks that content emitted by an earlier run, or earlier in the same run, becomes a placeholder
+func TestSeenStore(t *testing.T) {
+	shared := "package lib\n\nfunc Shared() {}\n"
+	sharedHash := fmt.Sprintf("%x", sha256.Sum256([]byte(shared)))
+	store := filepath.Join(createTempDir(t, "colligo_seen"), "seen.txt")
+
+	first := createFixtureRepo(t, map[string]string{
+		"main.go":            "package main\n",
+		"vendor/lib/lib.go":  shared,
+		"vendor/copy/lib.go": shared,
+	})
+	output := runAndReadOutput(t, &Config{RepoPath: first, SeenStore: store})
+	if count := strings.Count(output, shared); count != 1 {
+		t.Errorf("Expected one copy of the shared file in the first run, got %d", count)
+	}
+	if !strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash("vendor/lib/lib.go")+"\n\n# DUPLICATE OF: vendor/copy/lib.go (sha256="+sharedHash+")\n") {
+		t.Errorf("Expected the second copy to point at the first, got:\n%s", output)
+	}
+
+	second := createFixtureRepo(t, map[string]string{
+		"app.go":       "package app\n",
+		"third/lib.go": shared,
+	})
+	output = runAndReadOutput(t, &Config{RepoPath: second, SeenStore: store})
+	if strings.Contains(output, shared) {
+		t.Errorf("Expected the shared file to be skipped in the second run, got:\n%s", output)
+	}
+	if !strings.Contains(output, "# BEGIN FILE: "+filepath.FromSlash("third/lib.go")+"\n\n# ALREADY EMITTED (sha256="+sharedHash+")\n") {
+		t.Errorf("Expected a placeholder for the previously emitted file, got:\n%s", output)
+	}
+	if !strings.Contains(output, "package app\n") {
+		t.Errorf("Expected new content to be written, got:\n%s", output)
+	}
+
+	data, err := os.ReadFile(store)
+	if err != nil {
+		t.Fatalf("Failed to read seen store: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 3 {
+		t.Errorf("Expected three hashes in the store, got %q", lines)
+	}
+}
+
+// TestSeenStoreInvalid checks that a corrupt store fails the run
+func TestSeenStoreInvalid(t *testing.T) {
+	store := filepath.Join(createTempDir(t, "colligo_seen"), "seen.txt")
+	if err := os.WriteFile(store, []byte("not-a-hash\n"), 0644); err != nil {
+		t.Fatalf("Failed to write seen store: %v", err)
+	}
+	repoDir := createFixtureRepo(t, map[string]string{"a.txt": "a\n"})
+	cfg := &Config{RepoPath: repoDir, OutputFile: filepath.Join(createTempDir(t, "colligo_output"), "combined.txt"), SeenStore: store}
+	if err := run(context.Background(), getLogger(), cfg); err == nil || !strings.Contains(err.Error(), "seen.txt:1") {
+		t.Errorf("Expected an error naming the bad line, got %v", err)
+	}
+}
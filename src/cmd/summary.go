@@ -0,0 +1,188 @@
+// File: src/cmd/summary.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// runStats aggregates information about the files written during a run
+type runStats struct {
+	Files           int          `json:"files"`
+	TotalBytes      int64        `json:"totalBytes"`
+	EstimatedTokens int          `json:"estimatedTokens"`
+	SizeHistogram   []sizeBucket `json:"sizeHistogram,omitempty"`
+	TopFiles        *topFiles    `json:"topFiles,omitempty"`
+	sizes           []int64
+	files           []fileSummary
+}
+
+// fileSummary describes one written file for -summary-top-n
+type fileSummary struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Lines   int       `json:"lines"`
+}
+
+// topFiles holds the files that stand out in each -summary-top-n category
+type topFiles struct {
+	Largest          []fileSummary `json:"largest"`
+	RecentlyModified []fileSummary `json:"recentlyModified"`
+	MostLines        []fileSummary `json:"mostLines"`
+}
+
+// sizeBucket counts the files whose size falls within [Min, Max)
+type sizeBucket struct {
+	Label string `json:"bucket"`
+	Min   int64  `json:"-"`
+	Max   int64  `json:"-"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Width of the longest bar in the text histogram
+const histogramWidth = 40
+
+// add records a written file and its written content in the stats
+func (s *runStats) add(entry fileEntry, content []byte) {
+	s.Files++
+	s.TotalBytes += entry.Size
+	s.EstimatedTokens += estimateTokens(entry.Size)
+	s.sizes = append(s.sizes, entry.Size)
+	s.files = append(s.files, fileSummary{
+		Path:    filepath.ToSlash(entry.RelativePath),
+		Size:    entry.Size,
+		ModTime: entry.ModTime,
+		Lines:   countLines(content),
+	})
+}
+
+// countLines counts the lines in content, including a final line without a newline
+func countLines(content []byte) int {
+	lines := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+// buildTopFiles picks the n largest, most recently modified and longest files. Ties
+// are broken by path so the summary is stable.
+func buildTopFiles(files []fileSummary, n int) *topFiles {
+	top := func(greater func(a, b fileSummary) int) []fileSummary {
+		sorted := slices.Clone(files)
+		slices.SortFunc(sorted, func(a, b fileSummary) int {
+			if c := greater(b, a); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Path, b.Path)
+		})
+		return sorted[:min(n, len(sorted))]
+	}
+	return &topFiles{
+		Largest:          top(func(a, b fileSummary) int { return cmp.Compare(a.Size, b.Size) }),
+		RecentlyModified: top(func(a, b fileSummary) int { return a.ModTime.Compare(b.ModTime) }),
+		MostLines:        top(func(a, b fileSummary) int { return cmp.Compare(a.Lines, b.Lines) }),
+	}
+}
+
+// buildSizeHistogram sorts sizes into the fixed buckets used by -emit-size-histogram
+func buildSizeHistogram(sizes []int64) []sizeBucket {
+	const kb = 1024
+	const mb = 1024 * kb
+	buckets := []sizeBucket{
+		{Label: "<1KB", Min: 0, Max: kb},
+		{Label: "1-10KB", Min: kb, Max: 10 * kb},
+		{Label: "10-100KB", Min: 10 * kb, Max: 100 * kb},
+		{Label: "100KB-1MB", Min: 100 * kb, Max: mb},
+		{Label: ">1MB", Min: mb, Max: -1},
+	}
+	for _, size := range sizes {
+		for i := range buckets {
+			if size >= buckets[i].Min && (buckets[i].Max < 0 || size < buckets[i].Max) {
+				buckets[i].Files++
+				buckets[i].Bytes += size
+				break
+			}
+		}
+	}
+	return buckets
+}
+
+// summaryLines renders the summary as plain lines, for formats to decorate
+func summaryLines(stats *runStats) []string {
+	lines := []string{
+		fmt.Sprintf("Files: %d", stats.Files),
+		fmt.Sprintf("Total bytes: %d", stats.TotalBytes),
+		fmt.Sprintf("Estimated tokens: %d", stats.EstimatedTokens),
+	}
+
+	if stats.SizeHistogram != nil {
+		lines = append(lines, "", "Size histogram:")
+		maxFiles := 0
+		for _, bucket := range stats.SizeHistogram {
+			maxFiles = max(maxFiles, bucket.Files)
+		}
+		for _, bucket := range stats.SizeHistogram {
+			bar := 0
+			if maxFiles > 0 {
+				bar = bucket.Files * histogramWidth / maxFiles
+			}
+			// Make sure non-empty buckets are always visible
+			if bucket.Files > 0 && bar == 0 {
+				bar = 1
+			}
+			lines = append(lines, fmt.Sprintf("  %-10s %6d files %12d bytes  %s", bucket.Label, bucket.Files, bucket.Bytes, strings.Repeat("#", bar)))
+		}
+	}
+
+	if stats.TopFiles != nil {
+		lines = append(lines, "", "Largest files:")
+		for _, file := range stats.TopFiles.Largest {
+			lines = append(lines, fmt.Sprintf("  %12d bytes  %s", file.Size, file.Path))
+		}
+		lines = append(lines, "", "Most recently modified files:")
+		for _, file := range stats.TopFiles.RecentlyModified {
+			lines = append(lines, fmt.Sprintf("  %s  %s", file.ModTime.Format("2006-01-02 15:04:05"), file.Path))
+		}
+		lines = append(lines, "", "Files with the most lines:")
+		for _, file := range stats.TopFiles.MostLines {
+			lines = append(lines, fmt.Sprintf("  %12d lines  %s", file.Lines, file.Path))
+		}
+	}
+	return lines
+}
+
+// writeSummary appends the summary section to the combined output as comment lines
+// in the style of markers
+func writeSummary(writer *bufio.Writer, stats *runStats, markers fileMarkers) error {
+	var b strings.Builder
+	b.WriteString("\n\n" + markers.comment("SUMMARY") + "\n")
+	for _, line := range summaryLines(stats) {
+		if line == "" {
+			b.WriteString(strings.TrimRight(markers.open, " ") + markers.suffix + "\n")
+			continue
+		}
+		b.WriteString(markers.comment(line))
+	}
+	_, err := writer.WriteString(b.String())
+	return err
+}
+
+// writeStatsFile writes the run statistics as JSON
+func writeStatsFile(path string, stats *runStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,148 @@
+// File: src/cmd/summary_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper function to write a fixture file of the given size
+func writeSizedFile(t *testing.T, path string, size int) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create fixture directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+		t.Fatalf("Failed to write fixture file: %v", err)
+	}
+}
+
+// TestBuildSizeHistogram checks that sizes land in the correct buckets
+func TestBuildSizeHistogram(t *testing.T) {
+	sizes := []int64{0, 1023, 1024, 10239, 10240, 102400, 1048575, 1048576}
+	buckets := buildSizeHistogram(sizes)
+
+	expectedFiles := []int{2, 2, 1, 2, 1}
+	expectedBytes := []int64{1023, 1024 + 10239, 10240, 102400 + 1048575, 1048576}
+	for i, bucket := range buckets {
+		if bucket.Files != expectedFiles[i] || bucket.Bytes != expectedBytes[i] {
+			t.Errorf("Bucket %s: expected %d files / %d bytes, got %d files / %d bytes",
+				bucket.Label, expectedFiles[i], expectedBytes[i], bucket.Files, bucket.Bytes)
+		}
+	}
+}
+
+// TestSizeHistogramRun checks the histogram in the summary and stats file for a fixture repo
+func TestSizeHistogramRun(t *testing.T) {
+	logger := getLogger()
+	repoDir := createTempDir(t, "colligo_histogram_repo")
+	outDir := createTempDir(t, "colligo_histogram_out")
+
+	writeSizedFile(t, filepath.Join(repoDir, "tiny.txt"), 100)
+	writeSizedFile(t, filepath.Join(repoDir, "small.txt"), 200)
+	writeSizedFile(t, filepath.Join(repoDir, "medium.txt"), 5*1024)
+	writeSizedFile(t, filepath.Join(repoDir, "sub", "large.txt"), 50*1024)
+	writeSizedFile(t, filepath.Join(repoDir, "huge.txt"), 2*1024*1024)
+
+	cfg := &Config{
+		RepoPath:      repoDir,
+		OutputFile:    filepath.Join(outDir, "combined.txt"),
+		Summary:       true,
+		StatsFile:     filepath.Join(outDir, "stats.json"),
+		SizeHistogram: true,
+	}
+	if err := run(context.Background(), logger, cfg); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	data, err := os.ReadFile(cfg.StatsFile)
+	if err != nil {
+		t.Fatalf("Failed to read stats file: %v", err)
+	}
+	var stats struct {
+		Files         int `json:"files"`
+		SizeHistogram []struct {
+			Bucket string `json:"bucket"`
+			Files  int    `json:"files"`
+			Bytes  int64  `json:"bytes"`
+		} `json:"sizeHistogram"`
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		t.Fatalf("Failed to parse stats file: %v", err)
+	}
+
+	if stats.Files != 5 {
+		t.Errorf("Expected 5 files, got %d", stats.Files)
+	}
+	expected := map[string]int{"<1KB": 2, "1-10KB": 1, "10-100KB": 1, "100KB-1MB": 0, ">1MB": 1}
+	if len(stats.SizeHistogram) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %d", len(expected), len(stats.SizeHistogram))
+	}
+	for _, bucket := range stats.SizeHistogram {
+		if bucket.Files != expected[bucket.Bucket] {
+			t.Errorf("Bucket %s: expected %d files, got %d", bucket.Bucket, expected[bucket.Bucket], bucket.Files)
+		}
+	}
+
+	output, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(output), "# Size histogram:") {
+		t.Errorf("Expected the summary to contain the size histogram")
+	}
+}
+
+// TestSummaryTopN checks the top files by size, modification time and line count
+func TestSummaryTopN(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"big.txt":     strings.Repeat("x", 500) + "\n",
+		"long.txt":    strings.Repeat("a\n", 100),
+		"fresh.txt":   "new\n",
+		"medium.txt":  strings.Repeat("y\n", 60),
+		"ancient.txt": "old",
+	})
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	modTimes := map[string]time.Time{
+		"big.txt":     base,
+		"long.txt":    base.Add(time.Hour),
+		"fresh.txt":   base.Add(48 * time.Hour),
+		"medium.txt":  base.Add(24 * time.Hour),
+		"ancient.txt": base.Add(-24 * time.Hour),
+	}
+	for name, modTime := range modTimes {
+		if err := os.Chtimes(filepath.Join(repoDir, name), modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+	}
+
+	cfg := &Config{RepoPath: repoDir, Summary: true, SummaryTopN: 2}
+	output := runAndReadOutput(t, cfg)
+
+	stamp := func(name string) string { return modTimes[name].Local().Format("2006-01-02 15:04:05") }
+	expected := []string{
+		fmt.Sprintf("# Largest files:\n#   %12d bytes  big.txt\n#   %12d bytes  long.txt\n", 501, 200),
+		fmt.Sprintf("# Most recently modified files:\n#   %s  fresh.txt\n#   %s  medium.txt\n", stamp("fresh.txt"), stamp("medium.txt")),
+		fmt.Sprintf("# Files with the most lines:\n#   %12d lines  long.txt\n#   %12d lines  medium.txt\n", 100, 60),
+	}
+	for _, section := range expected {
+		if !strings.Contains(output, section) {
+			t.Errorf("Expected the summary to contain:\n%s\ngot:\n%s", section, output)
+		}
+	}
+}
+
+// TestCountLines checks line counting with and without a trailing newline
+func TestCountLines(t *testing.T) {
+	cases := map[string]int{"": 0, "a": 1, "a\n": 1, "a\nb": 2, "a\nb\n": 2, "\n\n": 2}
+	for content, expected := range cases {
+		if got := countLines([]byte(content)); got != expected {
+			t.Errorf("countLines(%q) = %d, expected %d", content, got, expected)
+		}
+	}
+}
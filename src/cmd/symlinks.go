@@ -0,0 +1,31 @@
+// File: src/cmd/symlinks.go
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+)
+
+// markSymlinks turns symlinks whose resolved target is itself an included file into
+// placeholders pointing at that file, so its content is only written once
+func markSymlinks(logger *slog.Logger, entries []fileEntry) {
+	files := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.Symlink {
+			files[entry.Path] = entry.RelativePath
+		}
+	}
+	for i := range entries {
+		entry := &entries[i]
+		if !entry.Symlink || entry.Note != "" {
+			continue
+		}
+		target, ok := files[entry.Path]
+		if !ok {
+			continue
+		}
+		logger.Debug("Replacing symlink with placeholder", "file", entry.RelativePath, "target", target)
+		entry.Note = fmt.Sprintf("# SYMLINK TO: %s", filepath.ToSlash(target))
+	}
+}
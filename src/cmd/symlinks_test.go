@@ -0,0 +1,40 @@
+// File: src/cmd/symlinks_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPreferFileOverSymlink checks that a symlink to an included file becomes a placeholder
+func TestPreferFileOverSymlink(t *testing.T) {
+	content := "shared symlinked content\n"
+	repoDir := createFixtureRepo(t, map[string]string{"real.txt": content})
+	outsideDir := createFixtureRepo(t, map[string]string{"outside.txt": "outside content\n"})
+	if err := os.Symlink(filepath.Join(repoDir, "real.txt"), filepath.Join(repoDir, "alias.txt")); err != nil {
+		t.Skipf("Symlinks are not available: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outsideDir, "outside.txt"), filepath.Join(repoDir, "external.txt")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, PreferFileOverSymlink: true})
+
+	if count := strings.Count(output, content); count != 1 {
+		t.Errorf("Expected one full copy of the content, got %d", count)
+	}
+	if !strings.Contains(output, "# BEGIN FILE: alias.txt\n\n# SYMLINK TO: real.txt\n") {
+		t.Errorf("Expected a symlink placeholder for alias.txt, got:\n%s", output)
+	}
+	// A symlink whose target is not itself included keeps its content
+	if !strings.Contains(output, "outside content\n") {
+		t.Errorf("Expected the content of external.txt to be written, got:\n%s", output)
+	}
+
+	output = runAndReadOutput(t, &Config{RepoPath: repoDir})
+	if count := strings.Count(output, content); count != 2 {
+		t.Errorf("Expected both copies without -prefer-file-over-symlink, got %d", count)
+	}
+}
@@ -0,0 +1,242 @@
+// File: src/cmd/transforms.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// stripComments removes whole-line comments for languages with a known line comment
+// marker. Trailing comments and block comments are left alone, since removing them
+// safely requires a real tokenizer for each language.
+func stripComments(content []byte, relativePath string) []byte {
+	marker := languageFor(relativePath).LineComment
+	if marker == "" {
+		return content
+	}
+
+	var out bytes.Buffer
+	for i, line := range bytes.SplitAfter(content, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		// Keep a shebang so scripts still say what runs them
+		if i == 0 && bytes.HasPrefix(trimmed, []byte("#!")) {
+			out.Write(line)
+			continue
+		}
+		if bytes.HasPrefix(trimmed, []byte(marker)) {
+			continue
+		}
+		out.Write(line)
+	}
+	return out.Bytes()
+}
+
+// headLines keeps only the first n lines of content
+func headLines(content []byte, n int) []byte {
+	if n <= 0 {
+		return content
+	}
+	end := 0
+	for i := 0; i < n; i++ {
+		next := bytes.IndexByte(content[end:], '\n')
+		if next < 0 {
+			return content
+		}
+		end += next + 1
+	}
+	return content[:end]
+}
+
+// transformer is one step of the content pipeline. Each step decides from the entry
+// whether it is enabled for the file and returns content unchanged if not.
+type transformer struct {
+	name  string
+	apply func(content []byte, entry fileEntry) []byte
+}
+
+// Every available transform, in the default order: comments are stripped before
+// counting lines for -head, Markdown links are rewritten and data arrays collapsed
+// before the text is cut, truncation sees the final text, and the Go doc summary
+// goes in last so nothing strips or truncates it
+var defaultTransforms = []transformer{
+	{"strip-comments", func(content []byte, entry fileEntry) []byte {
+		if !entry.StripComments {
+			return content
+		}
+		// A doc summary added by an earlier step is a comment too, but keep it
+		summary, rest := splitDocSummary(content, entry)
+		return append(summary, stripComments(rest, entry.RelativePath)...)
+	}},
+	{"rewrite-md-links", func(content []byte, entry fileEntry) []byte {
+		if !entry.RewriteMDLinks || languageFor(entry.RelativePath).Name != "markdown" {
+			return content
+		}
+		return rewriteMarkdownLinks(content, entry.RelativePath, entry.RepoURL)
+	}},
+	{"collapse-arrays", func(content []byte, entry fileEntry) []byte {
+		if entry.CollapseArrays <= 0 {
+			return content
+		}
+		switch languageFor(entry.RelativePath).Name {
+		case "json":
+			return collapseJSONArrays(content, entry.CollapseArrays)
+		case "yaml":
+			return collapseYAMLSequences(content, entry.CollapseArrays)
+		}
+		return content
+	}},
+	{"head", func(content []byte, entry fileEntry) []byte {
+		return headLines(content, entry.Head)
+	}},
+	{"truncate", func(content []byte, entry fileEntry) []byte {
+		if entry.TokenLimit <= 0 {
+			return content
+		}
+		return truncateToTokens(content, entry.RelativePath, entry.TokenLimit, entry.TruncateStrategy)
+	}},
+	{"go-doc-summary", func(content []byte, entry fileEntry) []byte {
+		if entry.DocSummary == "" {
+			return content
+		}
+		return append([]byte(entry.DocSummary+"\n"), content...)
+	}},
+}
+
+// transformNames lists the transforms in their default order
+func transformNames() []string {
+	names := make([]string, len(defaultTransforms))
+	for i, t := range defaultTransforms {
+		names[i] = t.name
+	}
+	return names
+}
+
+// newTransformChain orders the transforms as listed in order. Transforms left out of
+// the list run afterwards in their default order, so a partial list only moves the
+// named steps.
+func newTransformChain(order []string) ([]transformer, error) {
+	var chain []transformer
+	used := make(map[string]bool)
+	for _, name := range order {
+		index := slices.IndexFunc(defaultTransforms, func(t transformer) bool { return t.name == name })
+		if index < 0 {
+			return nil, fmt.Errorf("unknown transform %q (supported: %s)", name, strings.Join(transformNames(), ", "))
+		}
+		if used[name] {
+			return nil, fmt.Errorf("transform %q listed more than once", name)
+		}
+		used[name] = true
+		chain = append(chain, defaultTransforms[index])
+	}
+	for _, t := range defaultTransforms {
+		if !used[t.name] {
+			chain = append(chain, t)
+		}
+	}
+	return chain, nil
+}
+
+// runTransforms passes content through each step of chain in turn. The Go doc
+// summary is taken from the content before any step, since stripping comments or
+// truncating would remove the documentation it summarizes.
+func runTransforms(chain []transformer, content []byte, entry fileEntry) []byte {
+	if entry.DocSummary == "" {
+		entry.DocSummary = docSummary(content, entry)
+	}
+	for _, t := range chain {
+		content = t.apply(content, entry)
+	}
+	return content
+}
+
+// Values accepted by -sanitize-utf8
+var sanitizeUTF8Modes = []string{"replace", "strip"}
+
+// sanitizeUTF8 replaces each run of invalid UTF-8 bytes with U+FFFD, or drops it when
+// strip is set, and returns the number of runs fixed
+func sanitizeUTF8(content []byte, strip bool) ([]byte, int) {
+	if utf8.Valid(content) {
+		return content, 0
+	}
+	replacement := []byte(string(utf8.RuneError))
+	if strip {
+		replacement = nil
+	}
+
+	out := make([]byte, 0, len(content))
+	fixed := 0
+	invalid := false
+	for len(content) > 0 {
+		r, size := utf8.DecodeRune(content)
+		if r == utf8.RuneError && size == 1 {
+			if !invalid {
+				out = append(out, replacement...)
+				fixed++
+			}
+			invalid = true
+		} else {
+			out = append(out, content[:size]...)
+			invalid = false
+		}
+		content = content[size:]
+	}
+	return out, fixed
+}
+
+// applyTransforms runs the transforms enabled for a file over its content, in the
+// entry's transform order. The order is validated before any file is read.
+func applyTransforms(content []byte, entry fileEntry) []byte {
+	chain, err := newTransformChain(entry.TransformOrder)
+	if err != nil {
+		chain = defaultTransforms
+	}
+	return runTransforms(chain, content, entry)
+}
+
+// docSummary returns the -emit-go-doc-summaries line of a file, or "" when the file
+// gets none
+func docSummary(content []byte, entry fileEntry) string {
+	if !entry.GoDocSummary || !strings.EqualFold(filepath.Ext(entry.RelativePath), ".go") {
+		return ""
+	}
+	return goDocSummary(content, entry.RelativePath)
+}
+
+// splitDocSummary separates the doc summary line at the start of content, if any,
+// from the rest of the content
+func splitDocSummary(content []byte, entry fileEntry) (summary, rest []byte) {
+	line := entry.DocSummary + "\n"
+	if entry.DocSummary == "" || !bytes.HasPrefix(content, []byte(line)) {
+		return nil, content
+	}
+	return []byte(line), content[len(line):]
+}
+
+// goDocSummary returns a "// SUMMARY:" comment holding the package documentation of
+// a Go source file, collapsed onto one line
+func goDocSummary(content []byte, relativePath string) string {
+	const missing = "// SUMMARY: (no documentation)"
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Base(relativePath), content, parser.ParseComments|parser.PackageClauseOnly)
+	if err != nil {
+		return missing
+	}
+	pkg, err := doc.NewFromFiles(fset, []*ast.File{file}, file.Name.Name)
+	if err != nil {
+		return missing
+	}
+	summary := strings.Join(strings.Fields(pkg.Doc), " ")
+	if summary == "" {
+		return missing
+	}
+	return "// SUMMARY: " + summary
+}
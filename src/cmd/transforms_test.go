@@ -0,0 +1,179 @@
+// File: src/cmd/transforms_test.go
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestStripComments checks that whole-line comments are removed per language
+func TestStripComments(t *testing.T) {
+	cases := []struct {
+		name     string
+		path     string
+		input    string
+		expected string
+	}{
+		{"Go", "main.go", "// doc\npackage main\n\t// inner\nfunc f() {} // trailing\n", "package main\nfunc f() {} // trailing\n"},
+		{"Python Shebang", "run.py", "#!/usr/bin/env python\n# comment\nprint(1)\n", "#!/usr/bin/env python\nprint(1)\n"},
+		{"Unknown Language", "notes.txt", "# not a comment\n", "# not a comment\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := string(stripComments([]byte(c.input), c.path))
+			if got != c.expected {
+				t.Errorf("Expected %q but got %q", c.expected, got)
+			}
+		})
+	}
+}
+
+// TestHeadLines checks that only the first lines are kept
+func TestHeadLines(t *testing.T) {
+	content := []byte("one\ntwo\nthree\n")
+	cases := []struct {
+		n        int
+		expected string
+	}{
+		{0, "one\ntwo\nthree\n"},
+		{1, "one\n"},
+		{2, "one\ntwo\n"},
+		{5, "one\ntwo\nthree\n"},
+	}
+
+	for _, c := range cases {
+		if got := string(headLines(content, c.n)); got != c.expected {
+			t.Errorf("headLines(%d) = %q, expected %q", c.n, got, c.expected)
+		}
+	}
+}
+
+// TestGoDocSummaries checks that the package documentation is extracted and placed after the header
+func TestGoDocSummaries(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"widget/widget.go": "// Package widget renders widgets.\n//\n// It supports  several   kinds.\npackage widget\n\nfunc Render() {}\n",
+		"widget/helper.go": "package widget\n\n// helper is not package documentation\nfunc helper() {}\n",
+		"README.md":        "# Widgets\n",
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, EmitGoDocSummaries: true})
+
+	documented := "# BEGIN FILE: " + filepath.FromSlash("widget/widget.go") + "\n\n// SUMMARY: Package widget renders widgets. It supports several kinds.\n// Package widget"
+	if !strings.Contains(output, documented) {
+		t.Errorf("Expected the package doc summary after the header, got:\n%s", output)
+	}
+	undocumented := "# BEGIN FILE: " + filepath.FromSlash("widget/helper.go") + "\n\n// SUMMARY: (no documentation)\npackage widget"
+	if !strings.Contains(output, undocumented) {
+		t.Errorf("Expected a placeholder summary for an undocumented file, got:\n%s", output)
+	}
+	if strings.Count(output, "// SUMMARY:") != 2 {
+		t.Errorf("Expected summaries only for Go files")
+	}
+}
+
+// TestTransformOrder checks that reordering the chain changes order-dependent results
+func TestTransformOrder(t *testing.T) {
+	content := []byte("// header comment\n// more comment\nfirst()\nsecond()\n")
+	entry := fileEntry{RelativePath: "main.go", StripComments: true, Head: 2}
+
+	cases := []struct {
+		order    []string
+		expected string
+	}{
+		// Stripping first lets -head count only code lines
+		{nil, "first()\nsecond()\n"},
+		{[]string{"strip-comments", "head"}, "first()\nsecond()\n"},
+		// Taking the head first keeps only comments, which are then stripped
+		{[]string{"head", "strip-comments"}, ""},
+	}
+	for _, c := range cases {
+		chain, err := newTransformChain(c.order)
+		if err != nil {
+			t.Fatalf("newTransformChain(%v) failed: %v", c.order, err)
+		}
+		if got := string(runTransforms(chain, content, entry)); got != c.expected {
+			t.Errorf("Order %v: expected %q, got %q", c.order, c.expected, got)
+		}
+	}
+
+	// -head counts the doc summary line only when the summary runs first
+	doc := []byte("// Package p does things.\npackage p\n\nvar x = 1\n")
+	docEntry := fileEntry{RelativePath: "p.go", GoDocSummary: true, Head: 2}
+	chain, _ := newTransformChain([]string{"go-doc-summary", "head"})
+	if got := string(runTransforms(chain, doc, docEntry)); got != "// SUMMARY: Package p does things.\n// Package p does things.\n" {
+		t.Errorf("Expected -head to count the summary line, got %q", got)
+	}
+	if got := string(applyTransforms(doc, docEntry)); got != "// SUMMARY: Package p does things.\n// Package p does things.\npackage p\n" {
+		t.Errorf("Expected the default order to add the summary after -head, got %q", got)
+	}
+
+	// The summary comes from the file as read, so stripping comments or truncating
+	// does not lose the documentation
+	for _, e := range []fileEntry{
+		{RelativePath: "p.go", GoDocSummary: true, StripComments: true},
+		{RelativePath: "p.go", GoDocSummary: true, TokenLimit: 1},
+	} {
+		if got := string(applyTransforms(doc, e)); !strings.HasPrefix(got, "// SUMMARY: Package p does things.\n") {
+			t.Errorf("Expected the summary of the original file, got %q", got)
+		}
+	}
+}
+
+// TestNewTransformChain checks validation and that omitted transforms keep their default order
+func TestNewTransformChain(t *testing.T) {
+	chain, err := newTransformChain([]string{"truncate"})
+	if err != nil {
+		t.Fatalf("newTransformChain failed: %v", err)
+	}
+	var names []string
+	for _, step := range chain {
+		names = append(names, step.name)
+	}
+	if strings.Join(names, ",") != "truncate,strip-comments,rewrite-md-links,collapse-arrays,head,go-doc-summary" {
+		t.Errorf("Unexpected chain order: %v", names)
+	}
+
+	if _, err := newTransformChain([]string{"minify"}); err == nil {
+		t.Errorf("Expected an unknown transform to be rejected")
+	}
+	if _, err := newTransformChain([]string{"head", "head"}); err == nil {
+		t.Errorf("Expected a duplicate transform to be rejected")
+	}
+}
+
+// TestSanitizeUTF8 checks replacing and stripping runs of invalid bytes
+func TestSanitizeUTF8(t *testing.T) {
+	content := []byte("ok \xff\xfe bad \xc3 cut é fine\x80")
+
+	replaced, fixed := sanitizeUTF8(content, false)
+	if string(replaced) != "ok � bad � cut é fine�" || fixed != 3 {
+		t.Errorf("Unexpected replacement result %q with %d fixes", replaced, fixed)
+	}
+	stripped, fixed := sanitizeUTF8(content, true)
+	if string(stripped) != "ok  bad  cut é fine" || fixed != 3 {
+		t.Errorf("Unexpected strip result %q with %d fixes", stripped, fixed)
+	}
+	if valid, fixed := sanitizeUTF8([]byte("héllo"), false); string(valid) != "héllo" || fixed != 0 {
+		t.Errorf("Expected valid content to be left alone")
+	}
+}
+
+// TestSanitizeUTF8Run checks that the combined output is valid UTF-8, even after truncation splits a character
+func TestSanitizeUTF8Run(t *testing.T) {
+	repoDir := createFixtureRepo(t, map[string]string{
+		"latin1.txt": "caf\xe9\n",
+		"split.txt":  strings.Repeat("é", 100),
+	})
+
+	output := runAndReadOutput(t, &Config{RepoPath: repoDir, SanitizeUTF8: "replace", PerFileTokenLimit: 5, PerFileTruncateStrategy: "head"})
+
+	if !utf8.ValidString(output) {
+		t.Errorf("Expected valid UTF-8 output, got %q", output)
+	}
+	if !strings.Contains(output, "caf�\n") {
+		t.Errorf("Expected the Latin-1 byte to be replaced, got %q", output)
+	}
+}
@@ -0,0 +1,128 @@
+// File: src/cmd/truncate.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Strategies accepted by -per-file-truncate-strategy
+var truncateStrategies = []string{"head", "tail", "head+tail", "signatures-only"}
+
+// validTruncateStrategy reports whether name is a known truncation strategy
+func validTruncateStrategy(name string) bool {
+	for _, strategy := range truncateStrategies {
+		if strategy == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Lines that start a top-level declaration, per language
+var signaturePatterns = map[string]*regexp.Regexp{
+	"go":         regexp.MustCompile(`^(package|import|func|type|const|var)\b`),
+	"python":     regexp.MustCompile(`^\s*(async\s+def|def|class)\b`),
+	"javascript": regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(async\s+)?(function|class|const|let)\b`),
+	"typescript": regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(async\s+)?(function|class|interface|type|enum|const|let)\b`),
+	"rust":       regexp.MustCompile(`^\s*(pub(\([^)]*\))?\s+)?(fn|struct|enum|trait|impl|mod|type|const)\b`),
+	"ruby":       regexp.MustCompile(`^\s*(def|class|module)\b`),
+}
+
+// truncateToTokens reduces content to roughly limit tokens using the given strategy.
+// Content already within the limit is returned unchanged.
+func truncateToTokens(content []byte, relativePath string, limit int, strategy string) []byte {
+	maxBytes := limit * bytesPerToken
+	if limit <= 0 || len(content) <= maxBytes {
+		return content
+	}
+
+	switch strategy {
+	case "tail":
+		tail := tailBytes(content, maxBytes)
+		return append([]byte(truncationMarker(len(content)-len(tail), len(content))), tail...)
+	case "head+tail":
+		head := headBytes(content, maxBytes/2)
+		tail := tailBytes(content[len(head):], maxBytes-len(head))
+		var out bytes.Buffer
+		out.Write(head)
+		out.WriteString(truncationMarker(len(content)-len(head)-len(tail), len(content)))
+		out.Write(tail)
+		return out.Bytes()
+	case "signatures-only":
+		signatures := extractSignatures(content, relativePath)
+		if len(signatures) > maxBytes {
+			signatures = headBytes(signatures, maxBytes)
+		}
+		return append(signatures, truncationMarker(len(content)-len(signatures), len(content))...)
+	default:
+		head := headBytes(content, maxBytes)
+		return append(head, truncationMarker(len(content)-len(head), len(content))...)
+	}
+}
+
+// truncationMarker is the line written in place of omitted content
+func truncationMarker(omitted int, total int) string {
+	return fmt.Sprintf("\n... [truncated: %d of %d bytes omitted] ...\n", omitted, total)
+}
+
+// headBytes returns at most n bytes from the start of content, cut at a line
+// boundary when possible and never inside a UTF-8 sequence
+func headBytes(content []byte, n int) []byte {
+	if len(content) <= n {
+		return content
+	}
+	if i := bytes.LastIndexByte(content[:n], '\n'); i >= 0 {
+		return content[:i+1]
+	}
+	for n > 0 && !utf8.RuneStart(content[n]) {
+		n--
+	}
+	return content[:n]
+}
+
+// tailBytes returns at most n bytes from the end of content, starting at a line
+// boundary when possible and never inside a UTF-8 sequence
+func tailBytes(content []byte, n int) []byte {
+	if len(content) <= n {
+		return content
+	}
+	start := len(content) - n
+	if i := bytes.IndexByte(content[start:], '\n'); i >= 0 && start+i+1 < len(content) {
+		return content[start+i+1:]
+	}
+	for start < len(content) && !utf8.RuneStart(content[start]) {
+		start++
+	}
+	return content[start:]
+}
+
+// extractSignatures keeps the lines that declare top-level symbols. Languages without
+// a known pattern fall back to unindented lines, which catches most declarations.
+func extractSignatures(content []byte, relativePath string) []byte {
+	pattern := signaturePatterns[languageFor(relativePath).Name]
+
+	var out bytes.Buffer
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		var keep bool
+		if pattern != nil {
+			keep = pattern.MatchString(line)
+		} else {
+			keep = line[0] != ' ' && line[0] != '\t' && trimmed != "}" && trimmed != "};" && trimmed != ")"
+		}
+		if keep {
+			out.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				out.WriteByte('\n')
+			}
+		}
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,62 @@
+// File: src/cmd/truncate_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTruncateToTokens checks each truncation strategy on a file over the limit
+func TestTruncateToTokens(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 20; i++ {
+		b.WriteString("line-")
+		b.WriteString(string(rune('a' + i)))
+		b.WriteString("\n")
+	}
+	content := []byte(b.String()) // 20 lines of 7 bytes
+
+	// A limit of 5 tokens keeps 20 bytes, i.e. two whole lines
+	head := string(truncateToTokens(content, "data.txt", 5, "head"))
+	if !strings.HasPrefix(head, "line-a\nline-b\n") || strings.Contains(head, "line-c") {
+		t.Errorf("Unexpected head truncation: %q", head)
+	}
+	if !strings.Contains(head, "[truncated: 126 of 140 bytes omitted]") {
+		t.Errorf("Expected a truncation marker, got %q", head)
+	}
+
+	tail := string(truncateToTokens(content, "data.txt", 5, "tail"))
+	if !strings.HasSuffix(tail, "line-s\nline-t\n") || strings.Contains(tail, "line-r") {
+		t.Errorf("Unexpected tail truncation: %q", tail)
+	}
+
+	both := string(truncateToTokens(content, "data.txt", 6, "head+tail"))
+	if !strings.HasPrefix(both, "line-a\n") || !strings.HasSuffix(both, "line-t\n") || !strings.Contains(both, "truncated") {
+		t.Errorf("Unexpected head+tail truncation: %q", both)
+	}
+
+	if got := truncateToTokens(content, "data.txt", 100, "head"); string(got) != string(content) {
+		t.Errorf("Expected content within the limit to be unchanged")
+	}
+}
+
+// TestSignaturesOnly checks that declarations survive signatures-only truncation
+func TestSignaturesOnly(t *testing.T) {
+	source := "package demo\n\n// Add adds.\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\ntype Point struct {\n\tX, Y int\n}\n\nfunc (p Point) Len() int {\n\treturn p.X + p.Y\n}\n"
+
+	got := string(truncateToTokens([]byte(source), "demo.go", 25, "signatures-only"))
+	for _, expected := range []string{"package demo\n", "func Add(a, b int) int {\n", "type Point struct {\n", "func (p Point) Len() int {\n"} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("Expected signature %q in %q", expected, got)
+		}
+	}
+	if strings.Contains(got, "return") {
+		t.Errorf("Expected function bodies to be dropped, got %q", got)
+	}
+
+	python := "import os\n\nclass Greeter:\n    def greet(self):\n        print('hi')\n" + strings.Repeat("# filler\n", 20)
+	got = string(truncateToTokens([]byte(python), "greet.py", 10, "signatures-only"))
+	if !strings.Contains(got, "class Greeter:\n") || !strings.Contains(got, "    def greet(self):\n") || strings.Contains(got, "print") {
+		t.Errorf("Unexpected Python signatures: %q", got)
+	}
+}